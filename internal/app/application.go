@@ -17,9 +17,11 @@ import (
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/driver"
 	metrics "github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/auth"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/consumer"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/logging"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/secrets"
 )
 
 var (
@@ -42,7 +44,7 @@ func startRESTService(ctx context.Context) {
 	}
 }
 
-func CloseKafkaProducers(kafkaProducer producer.Producer) {
+func CloseKafkaProducers(kafkaProducer producer.WriterCloser) {
 	if err := kafkaProducer.CloseWriter(); err != nil {
 		logger.Info("failed to close producers:", err.Error())
 	}
@@ -85,6 +87,10 @@ func StartApplication(version string) {
 		logger.Infof("Pprof is enabled")
 	}
 
+	if _, err := secrets.Default(); err != nil {
+		logger.Fatalf("Invalid secrets provider settings: %v", err)
+	}
+
 	ctx := context.Background()
 	ctx, cancel = context.WithCancel(ctx)
 	driver.RegisterShutdownSignalHandler(handleShutdownSignal)
@@ -96,37 +102,96 @@ func StartApplication(version string) {
 	metricCollectionHandler.SetupMetricCollector()
 	initMetrics()
 
-	kafkaSchedulerProducer := producer.NewProducer(configs.GetKafkaTLSOn(),
+	kafkaSecurity, err := auth.SettingsFromConfig(ctx)
+	if err != nil {
+		logger.Fatalf("Invalid kafka authentication settings: %v", err)
+	}
+
+	kafkaSchedulerProducer := producer.NewProducer(kafkaSecurity,
 		configs.GetKafkaBootstrapServers(), configs.GetHaulerProducerKafkaTopic(),
 		uuidgenerator.NewGoogleUUIDGenerator())
 
-	kafkaHarmonyProducer := producer.NewProducer(configs.GetKafkaTLSOn(),
+	kafkaHarmonyProducer := producer.NewProducer(kafkaSecurity,
 		configs.GetKafkaBootstrapServers(), configs.GetHaulerHarmonyKafkaTopic(),
 		uuidgenerator.NewGoogleUUIDGenerator())
 
+	var kafkaDeadLetter producer.Forwarder
+	if configs.GetHaulerDeadLetterTopic() != "" {
+		kafkaDeadLetter = producer.NewForwarder(kafkaSecurity,
+			configs.GetKafkaBootstrapServers(), configs.GetHaulerDeadLetterTopic())
+	}
+
+	var kafkaRetry producer.Forwarder
+	if configs.GetHaulerRetryTopic() != "" {
+		kafkaRetry = producer.NewForwarder(kafkaSecurity,
+			configs.GetKafkaBootstrapServers(), configs.GetHaulerRetryTopic())
+	}
+
+	// both consumers share the workers, so a customer is never collected from both topics at once
+	workerPool := consumer.NewWorkerPool(configs.GetHaulerConsumerKafkaTopic(), configs.GetKafkaConsumerWorkers(),
+		configs.GetKafkaSerializeCustomers())
+
 	kafkaConsumerDone := make(chan bool, 1)
 	kafkaConsumer := consumer.NewConsumer(
 		configs.GetHaulerConsumerKafkaTopic(),
 		configs.GetHaulerKafkaGroupID(),
-		configs.GetKafkaTLSOn(),
+		kafkaSecurity,
 		configs.GetKafkaBootstrapServers(),
 		kafkaSchedulerProducer,
 		kafkaHarmonyProducer,
+		kafkaDeadLetter,
+		kafkaRetry,
+		workerPool,
 	)
 	go kafkaConsumer.ReadAndProcessMessages(ctx, kafkaConsumerDone)
 
+	// the re-enqueued collections are processed by a consumer of their own, waiting for their retry delay
+	kafkaRetryConsumerDone := make(chan bool, 1)
+	if kafkaRetry != nil {
+		kafkaRetryConsumer := consumer.NewConsumer(
+			configs.GetHaulerRetryTopic(),
+			configs.GetHaulerKafkaGroupID(),
+			kafkaSecurity,
+			configs.GetKafkaBootstrapServers(),
+			kafkaSchedulerProducer,
+			kafkaHarmonyProducer,
+			kafkaDeadLetter,
+			kafkaRetry,
+			workerPool,
+		)
+		go kafkaRetryConsumer.ReadAndProcessMessages(ctx, kafkaRetryConsumerDone)
+	} else {
+		kafkaRetryConsumerDone <- true
+	}
+
 	// Block until a shutdown signal is received.
 	<-ctx.Done()
+	// Allow the Kafka consumers to clean up before exiting, the in-flight collections still publish
+	// their statuses and re-enqueue their retries through the producers
+	<-kafkaConsumerDone
+	<-kafkaRetryConsumerDone
 	CloseKafkaProducers(kafkaSchedulerProducer)
 	CloseKafkaProducers(kafkaHarmonyProducer)
-	// Allow the Kafka consumer to clean up before exiting
-	<-kafkaConsumerDone
+	if kafkaDeadLetter != nil {
+		CloseKafkaProducers(kafkaDeadLetter)
+	}
+	if kafkaRetry != nil {
+		CloseKafkaProducers(kafkaRetry)
+	}
 }
 
 func initMetrics() {
 	prometheus.MustRegister(metrics.KafkaDuration)
 	prometheus.MustRegister(metrics.KafkaConsumerEventsCnt)
 	prometheus.MustRegister(metrics.KafkaProducerEventsCnt)
+	prometheus.MustRegister(metrics.KafkaDeadLetterCnt)
+	prometheus.MustRegister(metrics.KafkaConsumerLag)
+	prometheus.MustRegister(metrics.KafkaConsumerBusyWorkers)
+	prometheus.MustRegister(metrics.KafkaDuplicateCnt)
+	prometheus.MustRegister(metrics.KafkaRetryCnt)
 	prometheus.MustRegister(metrics.UploadFileSize)
 	prometheus.MustRegister(metrics.AwsRequestDuration)
+	prometheus.MustRegister(metrics.RestRetryCnt)
+	prometheus.MustRegister(metrics.RestRateLimitWait)
+	prometheus.MustRegister(metrics.RestCircuitBreakerState)
 }
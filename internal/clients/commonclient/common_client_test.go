@@ -0,0 +1,61 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package commonclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils"
+)
+
+type fakeRestClient struct {
+	status int
+	body   string
+	header http.Header
+}
+
+func (f *fakeRestClient) SetBaseURL(string, string, string) {}
+
+func (f *fakeRestClient) SendRequest(_ context.Context, _ *http.Request) (*http.Response, error) {
+	header := f.header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: f.status, Header: header, Body: io.NopCloser(strings.NewReader(f.body))}, nil
+}
+
+func TestHandleRequestRejectsNon2xx(t *testing.T) {
+	client := &CommonClient{client: &fakeRestClient{
+		status: http.StatusForbidden,
+		body:   `{"message":"access denied"}` + strings.Repeat(" ", maxErrorBodyExcerpt),
+		header: http.Header{"X-Request-Id": []string{"req-1"}},
+	}}
+	req, _ := http.NewRequest(http.MethodGet, "https://api/api/v1/virtual-machines?limit=50", http.NoBody)
+
+	resp, status, err := client.HandleRequest(context.Background(), req, nil)
+	assert.Nil(t, resp)
+	assert.Equal(t, http.StatusForbidden, status)
+	var restErr utils.RestClientError
+	assert.True(t, errors.As(err, &restErr))
+	assert.Equal(t, http.StatusForbidden, restErr.StatusCode)
+	assert.Equal(t, "GET /api/v1/virtual-machines?limit=50", restErr.Endpoint)
+	assert.Equal(t, `{"message":"access denied"}`, restErr.Body)
+	assert.Equal(t, "req-1", restErr.RequestID)
+}
+
+func TestHandleRequestAccepts2xx(t *testing.T) {
+	client := &CommonClient{client: &fakeRestClient{status: http.StatusOK, body: "{}"}}
+	req, _ := http.NewRequest(http.MethodGet, "https://api/api/v1/datastores", http.NoBody)
+
+	resp, status, err := client.HandleRequest(context.Background(), req, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotNil(t, resp)
+}
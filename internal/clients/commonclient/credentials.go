@@ -0,0 +1,122 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package commonclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+// Secret file names of a tenant in the credentials directory, prefixed by the customer ID
+const (
+	clientIDSuffix     = ".client_id"
+	clientSecretSuffix = ".client_secret"
+)
+
+var errUnknownTenant = errors.New("no client credentials found for customer")
+
+// CredentialResolver returns the client credentials used to collect a customer's assets.
+type CredentialResolver interface {
+	Resolve(ctx context.Context, applicationCustomerID, platformCustomerID string) (ClientCredentials, error)
+}
+
+// CredentialResolverFromConfig returns the resolver backed by the configured credentials directory
+// or JSON file. Without either, every customer is collected with the static client credentials.
+func CredentialResolverFromConfig() CredentialResolver {
+	if dir := configs.GetCredentialsDir(); dir != "" {
+		return &SecretsDirResolver{Dir: dir, TokenURL: configs.GetTokenURL()}
+	}
+	if path := configs.GetCredentialsFile(); path != "" {
+		return &FileResolver{Path: path, TokenURL: configs.GetTokenURL()}
+	}
+	return staticResolver{}
+}
+
+type staticResolver struct{}
+
+func (staticResolver) Resolve(context.Context, string, string) (ClientCredentials, error) {
+	return CredentialsFromConfig()
+}
+
+// SecretsDirResolver reads the credentials of a customer from the <customerID>.client_id and
+// <customerID>.client_secret files of a mounted secrets directory. Files are read on every
+// collection so rotated secrets are picked up without a restart.
+type SecretsDirResolver struct {
+	Dir      string
+	TokenURL string
+}
+
+func (r *SecretsDirResolver) Resolve(ctx context.Context, applicationCustomerID,
+	platformCustomerID string) (ClientCredentials, error) {
+	for _, customerID := range []string{applicationCustomerID, platformCustomerID} {
+		if customerID == "" || filepath.Base(customerID) != customerID {
+			continue
+		}
+		idPath := filepath.Join(r.Dir, customerID+clientIDSuffix)
+		if _, err := os.Stat(idPath); err != nil {
+			continue
+		}
+		clientID, err := readSecretFile(idPath)
+		if err != nil {
+			return ClientCredentials{}, err
+		}
+		clientSecret, err := readSecretFile(filepath.Join(r.Dir, customerID+clientSecretSuffix))
+		if err != nil {
+			return ClientCredentials{}, err
+		}
+		return ClientCredentials{TokenURL: r.TokenURL, ClientID: clientID, ClientSecret: clientSecret}, nil
+	}
+	logger.WithContext(ctx).Errorf("No credentials in %s for customer %s/%s", r.Dir, applicationCustomerID,
+		platformCustomerID)
+	return ClientCredentials{}, fmt.Errorf("%w: %s", errUnknownTenant, applicationCustomerID)
+}
+
+// FileResolver reads the credentials of a customer from a JSON file keyed by customer ID:
+//
+//	{"<customerID>": {"client_id": "...", "client_secret": "...", "token_url": "..."}}
+//
+// The token URL is optional and defaults to TokenURL.
+type FileResolver struct {
+	Path     string
+	TokenURL string
+}
+
+type tenantCredentials struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	TokenURL     string `json:"token_url"`
+}
+
+func (r *FileResolver) Resolve(ctx context.Context, applicationCustomerID,
+	platformCustomerID string) (ClientCredentials, error) {
+	content, err := os.ReadFile(r.Path)
+	if err != nil {
+		logger.WithContext(ctx).Errorf("Failed to read credentials file %s: %v", r.Path, err)
+		return ClientCredentials{}, err
+	}
+	tenants := make(map[string]tenantCredentials)
+	if err = json.Unmarshal(content, &tenants); err != nil {
+		logger.WithContext(ctx).Errorf("Failed to decode credentials file %s: %v", r.Path, err)
+		return ClientCredentials{}, err
+	}
+	for _, customerID := range []string{applicationCustomerID, platformCustomerID} {
+		tenant, found := tenants[customerID]
+		if customerID == "" || !found {
+			continue
+		}
+		credentials := ClientCredentials{TokenURL: tenant.TokenURL, ClientID: tenant.ClientID,
+			ClientSecret: tenant.ClientSecret}
+		if credentials.TokenURL == "" {
+			credentials.TokenURL = r.TokenURL
+		}
+		return credentials, nil
+	}
+	logger.WithContext(ctx).Errorf("No credentials in %s for customer %s/%s", r.Path, applicationCustomerID,
+		platformCustomerID)
+	return ClientCredentials{}, fmt.Errorf("%w: %s", errUnknownTenant, applicationCustomerID)
+}
@@ -0,0 +1,106 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package commonclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestSecretsDirResolver(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "app1.client_id", "id1\n")
+	writeFile(t, dir, "app1.client_secret", "secret1\n")
+	writeFile(t, dir, "platform2.client_id", "id2")
+	writeFile(t, dir, "platform2.client_secret", "secret2")
+	resolver := &SecretsDirResolver{Dir: dir, TokenURL: "https://sso/token"}
+	ctx := context.Background()
+
+	credentials, err := resolver.Resolve(ctx, "app1", "platform1")
+	assert.NoError(t, err)
+	assert.Equal(t, ClientCredentials{TokenURL: "https://sso/token", ClientID: "id1", ClientSecret: "secret1"},
+		credentials)
+
+	credentials, err = resolver.Resolve(ctx, "app2", "platform2")
+	assert.NoError(t, err)
+	assert.Equal(t, "id2", credentials.ClientID)
+
+	_, err = resolver.Resolve(ctx, "app3", "../app1")
+	assert.ErrorIs(t, err, errUnknownTenant)
+}
+
+func TestFileResolver(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "credentials.json", `{
+		"app1": {"client_id": "id1", "client_secret": "secret1"},
+		"platform2": {"client_id": "id2", "client_secret": "secret2", "token_url": "https://other/token"}
+	}`)
+	resolver := &FileResolver{Path: path, TokenURL: "https://sso/token"}
+	ctx := context.Background()
+
+	credentials, err := resolver.Resolve(ctx, "app1", "platform1")
+	assert.NoError(t, err)
+	assert.Equal(t, ClientCredentials{TokenURL: "https://sso/token", ClientID: "id1", ClientSecret: "secret1"},
+		credentials)
+
+	credentials, err = resolver.Resolve(ctx, "app2", "platform2")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://other/token", credentials.TokenURL)
+
+	_, err = resolver.Resolve(ctx, "app3", "platform3")
+	assert.ErrorIs(t, err, errUnknownTenant)
+}
+
+func TestNewCommonClientPerCollection(t *testing.T) {
+	dir := t.TempDir()
+	for _, customer := range []string{"app1", "app2"} {
+		writeFile(t, dir, customer+".client_id", customer+"-id")
+		writeFile(t, dir, customer+".client_secret", customer+"-secret")
+	}
+	resolver := &SecretsDirResolver{Dir: dir}
+	ctx := context.Background()
+
+	first, err := newCommonClient(ctx, resolver, "app1", "platform1")
+	assert.NoError(t, err)
+	second, err := newCommonClient(ctx, resolver, "app2", "platform2")
+	assert.NoError(t, err)
+	again, err := newCommonClient(ctx, resolver, "app1", "platform1")
+	assert.NoError(t, err)
+
+	assert.NotSame(t, first, again)
+	assert.Equal(t, "app1", first.customerID)
+	assert.Equal(t, "app2", second.customerID)
+	assert.NotSame(t, first.tokens, second.tokens)
+	assert.Same(t, first.tokens, again.tokens)
+	assert.Same(t, first.client, second.client)
+
+	writeFile(t, dir, "app1.client_secret", "app1-rotated")
+	rotated, err := newCommonClient(ctx, resolver, "app1", "platform1")
+	assert.NoError(t, err)
+	assert.NotSame(t, first.tokens, rotated.tokens, "a rotated secret gets a new token cache")
+	tokenProvidersMu.Lock()
+	assert.Same(t, rotated.tokens, tokenProviders["app1"], "the previous cache is dropped")
+	tokenProvidersMu.Unlock()
+
+	_, err = newCommonClient(ctx, resolver, "app3", "platform3")
+	assert.ErrorIs(t, err, errUnknownTenant)
+}
+
+func TestNewCommonClientUnknownTenant(t *testing.T) {
+	viper.Set("credentials_dir", t.TempDir())
+	t.Cleanup(func() { viper.Set("credentials_dir", "") })
+
+	client, err := NewCommonClient(context.Background(), "app1", "platform1")
+	assert.ErrorIs(t, err, errUnknownTenant)
+	assert.True(t, client == nil, "a failed client is a nil interface")
+}
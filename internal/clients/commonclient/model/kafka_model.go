@@ -26,6 +26,8 @@ type KafkaCollectionRequest struct {
 	Region                string `json:"region"`
 	ApplicationCustomerID string `json:"application_customer_id"`
 	ApplicationInstanceID string `json:"application_instance_id"`
+	// OutputFormat of the asset files, json, ndjson or parquet. Defaults to the configured format.
+	OutputFormat string `json:"output_format,omitempty"`
 }
 
 type HarmonyStatusRequest struct {
@@ -0,0 +1,107 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package commonclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+// RequestHandler sends a prepared request to the API, see CommonClient.HandleRequest.
+type RequestHandler interface {
+	HandleRequest(context.Context, *http.Request, map[string]string) (*http.Response, int, error)
+}
+
+// Page is the envelope of every paginated list returned by the API.
+type Page[T any] struct {
+	Items      []T `json:"items"`
+	PageLimit  int `json:"pageLimit"`
+	PageOffset int `json:"pageOffset"`
+	Total      int `json:"total"`
+}
+
+// PageHandler is called with the items of every fetched page, in order. Returning an
+// error stops the pagination and is returned to the caller.
+type PageHandler[T any] func(items []T) error
+
+// StreamPages walks a limit/offset paginated endpoint and hands every page to handle
+// without keeping the items around. Pagination stops once Total items were fetched or
+// when the API returns an empty page.
+func StreamPages[T any](ctx context.Context, client RequestHandler, baseURL, authHeader string,
+	handle PageHandler[T]) error {
+	pageOffset := PageOffset
+	fetched := 0
+	for {
+		query := map[string]string{
+			"limit":  fmt.Sprintf("%d", PageLimit),
+			"offset": fmt.Sprintf("%d", pageOffset),
+		}
+		page, err := FetchPage[T](ctx, client, baseURL, authHeader, query)
+		if err != nil {
+			return err
+		}
+		if len(page.Items) == 0 {
+			return nil
+		}
+		if err = handle(page.Items); err != nil {
+			return err
+		}
+		fetched += len(page.Items)
+		if fetched >= page.Total {
+			return nil
+		}
+		pageOffset += PageLimit
+	}
+}
+
+// FetchAll collects every item of a limit/offset paginated endpoint.
+func FetchAll[T any](ctx context.Context, client RequestHandler, baseURL, authHeader string) ([]T, error) {
+	var items []T
+	err := StreamPages(ctx, client, baseURL, authHeader, func(page []T) error {
+		items = append(items, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FetchPage issues a single GET on baseURL with the query parameters and decodes the page.
+func FetchPage[T any](ctx context.Context, client RequestHandler, baseURL, authHeader string,
+	query map[string]string) (*Page[T], error) {
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, bytes.NewReader(nil))
+	if reqErr != nil {
+		logger.WithContext(ctx).Error(reqErr.Error())
+		return nil, reqErr
+	}
+	if len(query) > 0 {
+		q := req.URL.Query()
+		for key, value := range query {
+			q.Add(key, value)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Close = true
+	// add authorization header to the req
+	if !configs.GetLocalCluster() {
+		req.Header.Add(RestAuthHeader, fmt.Sprintf("Bearer %s", authHeader))
+	}
+	resp, _, handleReqErr := client.HandleRequest(ctx, req, nil)
+	if handleReqErr != nil {
+		logger.WithContext(ctx).Error(handleReqErr.Error())
+		return nil, handleReqErr
+	}
+	defer resp.Body.Close()
+
+	page := &Page[T]{}
+	if decodeErr := json.NewDecoder(resp.Body).Decode(page); decodeErr != nil {
+		logger.WithContext(ctx).Error(decodeErr.Error())
+		return nil, decodeErr
+	}
+	return page, nil
+}
@@ -0,0 +1,90 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package commonclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	ID string `json:"id"`
+}
+
+// fakePager serves total items in pages of PageLimit, returning an empty page
+// once emptyAfter requests were served.
+type fakePager struct {
+	total      int
+	emptyAfter int
+	requests   []string
+	closed     int
+}
+
+type trackedBody struct {
+	io.Reader
+	pager *fakePager
+}
+
+func (b *trackedBody) Close() error {
+	b.pager.closed++
+	return nil
+}
+
+func (p *fakePager) HandleRequest(_ context.Context, req *http.Request,
+	_ map[string]string) (*http.Response, int, error) {
+	p.requests = append(p.requests, req.URL.RawQuery)
+	offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	page := Page[item]{PageLimit: limit, PageOffset: offset, Total: p.total, Items: []item{}}
+	if p.emptyAfter == 0 || len(p.requests) <= p.emptyAfter {
+		for i := offset; i < offset+limit && i < p.total; i++ {
+			page.Items = append(page.Items, item{ID: fmt.Sprintf("id-%d", i)})
+		}
+	}
+	body, _ := json.Marshal(page)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       &trackedBody{Reader: strings.NewReader(string(body)), pager: p},
+	}
+	return resp, resp.StatusCode, nil
+}
+
+func TestFetchAllStopsAtTotal(t *testing.T) {
+	pager := &fakePager{total: 2*PageLimit + 1}
+	items, err := FetchAll[item](context.Background(), pager, "/api/v1/items", "token")
+	assert.NoError(t, err)
+	assert.Len(t, items, 2*PageLimit+1)
+	assert.Equal(t, "id-0", items[0].ID)
+	assert.Len(t, pager.requests, 3)
+	assert.Equal(t, 3, pager.closed)
+	assert.Contains(t, pager.requests[2], fmt.Sprintf("offset=%d", 2*PageLimit))
+}
+
+func TestFetchAllStopsOnEmptyPage(t *testing.T) {
+	pager := &fakePager{total: 10 * PageLimit, emptyAfter: 1}
+	items, err := FetchAll[item](context.Background(), pager, "/api/v1/items", "token")
+	assert.NoError(t, err)
+	assert.Len(t, items, PageLimit)
+	assert.Len(t, pager.requests, 2)
+}
+
+func TestStreamPagesHandlerError(t *testing.T) {
+	pager := &fakePager{total: 3 * PageLimit}
+	errStop := errors.New("stop")
+	pages := 0
+	err := StreamPages(context.Background(), pager, "/api/v1/items", "token", func(items []item) error {
+		pages++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, pages)
+	assert.Len(t, pager.requests, 1)
+}
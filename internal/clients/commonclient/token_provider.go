@@ -0,0 +1,184 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package commonclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/secrets"
+)
+
+var (
+	errEmptyToken         = errors.New("token endpoint returned an empty access token")
+	errMissingCredentials = errors.New("client ID and secret are not set")
+)
+
+// TokenProvider supplies the bearer token of the API calls.
+type TokenProvider interface {
+	// Token returns a valid access token, fetching a new one when needed.
+	Token(ctx context.Context) (string, error)
+	// Invalidate drops token from the cache after the API rejected it.
+	Invalidate(token string)
+}
+
+// ClientCredentials identify the hauler against the SSO token endpoint.
+type ClientCredentials struct {
+	TokenURL, ClientID, ClientSecret string
+}
+
+// CredentialsFromConfig returns the configured token URL with the client credentials of the
+// secrets provider.
+func CredentialsFromConfig() (ClientCredentials, error) {
+	ctx := context.Background()
+	credentials := ClientCredentials{
+		TokenURL:     configs.GetTokenURL(),
+		ClientID:     secrets.GetOrEmpty(ctx, secrets.ClientID),
+		ClientSecret: secrets.GetOrEmpty(ctx, secrets.ClientSecret),
+	}
+	if credentials.ClientID == "" || credentials.ClientSecret == "" {
+		return ClientCredentials{}, errMissingCredentials
+	}
+	return credentials, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		logger.Errorf("Failed to read secret file %s: %v", path, err)
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// OAuth2TokenProvider fetches tokens with the OAuth2 client credentials grant and caches them
+// until refreshBefore their expiry. A token entering that window is still served while a new
+// one is fetched in the background. A single token is fetched at once, the callers needing one
+// meanwhile wait for it.
+type OAuth2TokenProvider struct {
+	credentials   ClientCredentials
+	httpClient    *http.Client
+	refreshBefore time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	// inFlight is the token being fetched, nil when none is
+	inFlight *tokenFetch
+}
+
+// tokenFetch is the outcome of a token request, set once done is closed.
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func NewOAuth2TokenProvider(credentials ClientCredentials, httpClient *http.Client,
+	refreshBefore time.Duration) *OAuth2TokenProvider {
+	return &OAuth2TokenProvider{
+		credentials:   credentials,
+		httpClient:    httpClient,
+		refreshBefore: refreshBefore,
+	}
+}
+
+func (p *OAuth2TokenProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	now := time.Now()
+	if p.token != "" && now.Before(p.expiry) {
+		token := p.token
+		if p.inFlight == nil && now.After(p.expiry.Add(-p.refreshBefore)) {
+			p.refresh()
+		}
+		p.mu.Unlock()
+		return token, nil
+	}
+	call := p.inFlight
+	if call == nil {
+		call = p.refresh()
+	}
+	p.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (p *OAuth2TokenProvider) Invalidate(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == token {
+		p.token = ""
+	}
+}
+
+// refresh starts fetching a new token to cache, p.mu must be held. The fetch is not bound to the
+// context of a caller since all of them wait for it.
+func (p *OAuth2TokenProvider) refresh() *tokenFetch {
+	call := &tokenFetch{done: make(chan struct{})}
+	p.inFlight = call
+	go func() {
+		defer close(call.done)
+		token, expiresIn, err := p.fetch(context.Background())
+		call.token, call.err = token, err
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.inFlight = nil
+		if err == nil {
+			p.token = token
+			p.expiry = time.Now().Add(expiresIn)
+		}
+	}()
+	return call
+}
+
+func (p *OAuth2TokenProvider) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.credentials.ClientID},
+		"client_secret": {p.credentials.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.credentials.TokenURL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		logger.WithContext(ctx).Errorf("Failed to create HTTP request: %v", err)
+		return "", 0, err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Close = true
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		logger.WithContext(ctx).Errorf("Failed to send HTTP request: %v", err)
+		return "", 0, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		restErr := newRestClientError(req, resp)
+		logger.WithContext(ctx).Errorf("Token request failed: %v", restErr)
+		return "", 0, restErr
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse struct {
+		AccessToken string  `json:"access_token"`
+		TokenType   string  `json:"token_type"`
+		ExpiresIn   float64 `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		logger.WithContext(ctx).Errorf("failed to decode token response: %v", err)
+		return "", 0, err
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", 0, errEmptyToken
+	}
+	return tokenResponse.AccessToken, time.Duration(tokenResponse.ExpiresIn * float64(time.Second)), nil
+}
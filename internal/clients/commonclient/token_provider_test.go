@@ -0,0 +1,172 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package commonclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenServer issues token-<n> tokens valid for expiresIn seconds.
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	var issued int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "id", r.PostForm.Get("client_id"))
+		n := atomic.AddInt32(&issued, 1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+func TestOAuth2TokenProviderCaches(t *testing.T) {
+	srv, issued := tokenServer(t, 3600)
+	provider := NewOAuth2TokenProvider(ClientCredentials{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret"},
+		srv.Client(), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		token, err := provider.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "token-1", token)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+
+	provider.Invalidate("token-1")
+	token, err := provider.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+}
+
+func TestOAuth2TokenProviderRefreshesProactively(t *testing.T) {
+	srv, issued := tokenServer(t, 30)
+	provider := NewOAuth2TokenProvider(ClientCredentials{TokenURL: srv.URL, ClientID: "id"}, srv.Client(), time.Minute)
+
+	token, err := provider.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	// the token expires within the refresh window, it is still served while a new one is fetched
+	token, err = provider.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(issued) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestOAuth2TokenProviderFetchesOnceAtATime(t *testing.T) {
+	srv, issued := tokenServer(t, 3600)
+	provider := NewOAuth2TokenProvider(ClientCredentials{TokenURL: srv.URL, ClientID: "id"}, srv.Client(), time.Minute)
+
+	tokens := func() []string {
+		start := make(chan struct{})
+		results := make([]string, 20)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				token, err := provider.Token(context.Background())
+				assert.NoError(t, err)
+				results[i] = token
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		return results
+	}
+	for _, token := range tokens() {
+		assert.Equal(t, "token-1", token)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+
+	provider.Invalidate("token-1")
+	for _, token := range tokens() {
+		assert.Equal(t, "token-2", token)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(issued), "the callers after an invalidation share one fetch")
+}
+
+func TestOAuth2TokenProviderRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	provider := NewOAuth2TokenProvider(ClientCredentials{TokenURL: srv.URL}, srv.Client(), time.Minute)
+	_, err := provider.Token(context.Background())
+	assert.Error(t, err)
+}
+
+// staticTokens hands out token-1, token-2... on every call following an invalidation.
+type staticTokens struct {
+	current int32
+}
+
+func (s *staticTokens) Token(context.Context) (string, error) {
+	return fmt.Sprintf("token-%d", atomic.LoadInt32(&s.current)), nil
+}
+
+func (s *staticTokens) Invalidate(string) {
+	atomic.AddInt32(&s.current, 1)
+}
+
+// authRestClient accepts only the token-2 bearer.
+type authRestClient struct {
+	calls []string
+}
+
+func (a *authRestClient) SetBaseURL(string, string, string) {}
+
+func (a *authRestClient) SendRequest(_ context.Context, req *http.Request) (*http.Response, error) {
+	a.calls = append(a.calls, req.URL.String()+" "+req.Header.Get(RestAuthHeader))
+	req.URL.Host, req.URL.Scheme = "api", "https"
+	status := http.StatusUnauthorized
+	if req.Header.Get(RestAuthHeader) == "Bearer token-2" {
+		status = http.StatusOK
+	}
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestHandleRequestReauthenticatesOnce(t *testing.T) {
+	rest := &authRestClient{}
+	client := &CommonClient{client: rest, tokens: &staticTokens{current: 1}}
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/datastores", http.NoBody)
+	req.Header.Set(RestAuthHeader, "Bearer stale")
+
+	resp, status, err := client.HandleRequest(context.Background(), req, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	resp.Body.Close()
+	assert.Equal(t, []string{"/api/v1/datastores Bearer token-1", "/api/v1/datastores Bearer token-2"}, rest.calls)
+
+	client.tokens = &staticTokens{current: 5}
+	rest.calls = nil
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/datastores", http.NoBody)
+	req.Header.Set(RestAuthHeader, "Bearer stale")
+	_, status, err = client.HandleRequest(context.Background(), req, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Len(t, rest.calls, 2)
+}
+
+func TestReadSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client_secret")
+	assert.NoError(t, os.WriteFile(path, []byte("secret\n"), 0o600))
+	secret, err := readSecretFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", secret)
+	_, err = readSecretFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package restclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+// CircuitState is the state of an endpoint's circuit breaker, exported as metric value.
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+var (
+	sharedBreaker     *CircuitBreaker
+	sharedBreakerOnce sync.Once
+)
+
+// SharedCircuitBreaker returns the breaker shared by every rest client of the process, so an
+// endpoint failing for one collection is skipped by all of them.
+func SharedCircuitBreaker() *CircuitBreaker {
+	sharedBreakerOnce.Do(func() {
+		sharedBreaker = NewCircuitBreaker(configs.GetCircuitBreakerFailures(), configs.GetCircuitBreakerCooldown())
+	})
+	return sharedBreaker
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// CircuitBreaker tracks the consecutive failures of every endpoint. An endpoint failing
+// threshold times in a row is short-circuited until cooldown elapsed, then a single probe
+// request is let through (half-open) to decide whether the circuit closes or opens again.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	circuits  map[string]*circuit
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+func (b *CircuitBreaker) circuit(endpoint string) *circuit {
+	c, found := b.circuits[endpoint]
+	if !found {
+		c = &circuit{}
+		b.circuits[endpoint] = c
+	}
+	return c
+}
+
+func (b *CircuitBreaker) setState(endpoint string, c *circuit, state CircuitState) {
+	c.state = state
+	prometheus.RestCircuitBreakerState.WithLabelValues(endpoint).Set(float64(state))
+}
+
+// Allow returns a utils.CircuitOpenError when requests to endpoint must not be sent.
+func (b *CircuitBreaker) Allow(endpoint string) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(endpoint)
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < b.cooldown {
+			return utils.CircuitOpenError{Endpoint: endpoint}
+		}
+		b.setState(endpoint, c, CircuitHalfOpen)
+		c.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if c.probing {
+			return utils.CircuitOpenError{Endpoint: endpoint}
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+// Record feeds the outcome of a request allowed on endpoint back to its circuit.
+func (b *CircuitBreaker) Record(ctx context.Context, endpoint string, response *http.Response, err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(endpoint)
+	c.probing = false
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// a half-open circuit lets the next request probe instead
+		return
+	}
+	if !isFailure(response, err) {
+		c.failures = 0
+		if c.state != CircuitClosed {
+			logger.WithContext(ctx).Infof("Circuit breaker closed for %s", endpoint)
+			b.setState(endpoint, c, CircuitClosed)
+		}
+		return
+	}
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= b.threshold {
+		logger.WithContext(ctx).Warnf("Circuit breaker opened for %s after %v failures", endpoint, c.failures)
+		c.openedAt = time.Now()
+		b.setState(endpoint, c, CircuitOpen)
+	}
+}
+
+// State returns the current state of endpoint's circuit.
+func (b *CircuitBreaker) State(endpoint string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.circuit(endpoint).state
+}
+
+// isFailure reports whether an outcome counts against the endpoint, requests timing out included.
+// Client errors, including 429 handled by the rate limiter, say nothing about its health, nor do
+// cancelled requests, which Record leaves out.
+func isFailure(response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return response.StatusCode >= http.StatusInternalServerError
+}
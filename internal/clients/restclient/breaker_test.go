@@ -0,0 +1,103 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package restclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	ctx := context.Background()
+	endpoint := "GET /backup-recovery/v1beta1/storeonces"
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+	failed := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, breaker.Allow(endpoint))
+		breaker.Record(ctx, endpoint, failed, nil)
+	}
+	assert.Equal(t, CircuitOpen, breaker.State(endpoint))
+	assert.ErrorIs(t, breaker.Allow(endpoint), utils.CircuitOpenError{})
+	assert.NoError(t, breaker.Allow("GET /api/v1/datastores"))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow(endpoint))
+	assert.Equal(t, CircuitHalfOpen, breaker.State(endpoint))
+	assert.ErrorIs(t, breaker.Allow(endpoint), utils.CircuitOpenError{}, "a single probe is let through")
+	breaker.Record(ctx, endpoint, failed, nil)
+	assert.Equal(t, CircuitOpen, breaker.State(endpoint))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow(endpoint))
+	breaker.Record(ctx, endpoint, &http.Response{StatusCode: http.StatusOK}, nil)
+	assert.Equal(t, CircuitClosed, breaker.State(endpoint))
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	ctx, cancel := context.WithCancel(context.Background())
+	breaker := NewCircuitBreaker(1, time.Minute)
+	breaker.Record(context.Background(), "e", &http.Response{StatusCode: http.StatusTooManyRequests}, nil)
+	cancel()
+	breaker.Record(ctx, "e", nil, errors.New("canceled"))
+	assert.Equal(t, CircuitClosed, breaker.State("e"))
+}
+
+func TestCircuitBreakerLeavesCancelledRequestsOut(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+	failed := &http.Response{StatusCode: http.StatusBadGateway}
+
+	breaker.Record(context.Background(), "e", failed, nil)
+	breaker.Record(cancelled, "e", nil, context.Canceled)
+	assert.Equal(t, CircuitClosed, breaker.State("e"))
+	breaker.Record(context.Background(), "e", failed, nil)
+	assert.Equal(t, CircuitOpen, breaker.State("e"), "a cancelled request does not reset the failures")
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow("e"))
+	breaker.Record(cancelled, "e", nil, context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, breaker.State("e"), "a cancelled probe does not close the circuit")
+	assert.NoError(t, breaker.Allow("e"), "the next request probes instead")
+}
+
+func TestCircuitBreakerCountsTimeouts(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	breaker := NewCircuitBreaker(1, time.Minute)
+
+	breaker.Record(ctx, "e", nil, context.DeadlineExceeded)
+	assert.Equal(t, CircuitOpen, breaker.State("e"))
+}
+
+func TestSendRequestShortCircuits(t *testing.T) {
+	srv, calls := failingServer(10, http.StatusInternalServerError, nil)
+	defer srv.Close()
+	client := newTestClient(srv.URL, RetryPolicy{MaxAttempts: 1})
+	client.breaker = NewCircuitBreaker(1, time.Minute)
+
+	req, _ := http.NewRequest(http.MethodGet, "/backup-recovery/v1beta1/storeonces", http.NoBody)
+	resp, err := client.SendRequest(context.Background(), req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodGet, "/backup-recovery/v1beta1/storeonces", http.NoBody)
+	_, err = client.SendRequest(context.Background(), req)
+	assert.ErrorIs(t, err, utils.CircuitOpenError{})
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
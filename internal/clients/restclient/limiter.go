@@ -0,0 +1,230 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package restclient
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+// API path families, each one is rate limited on its own
+const (
+	APIFamily              = "/api/v1/"
+	BackupRecoveryFamily   = "/backup-recovery/v1beta1/"
+	DisasterRecoveryFamily = "/disaster-recovery/v1beta1/"
+)
+
+const (
+	// minRateFraction is the lowest fraction of the configured rate a bucket slows down to.
+	minRateFraction = 0.1
+	// recoveryFactor raises the rate of a slowed down bucket after every successful call.
+	recoveryFactor = 1.05
+)
+
+type customerIDKey struct{}
+
+var (
+	sharedLimiter     *RateLimiter
+	sharedLimiterOnce sync.Once
+)
+
+// WithCustomerID returns a context whose requests are rate limited against customerID's budget.
+func WithCustomerID(ctx context.Context, customerID string) context.Context {
+	return context.WithValue(ctx, customerIDKey{}, customerID)
+}
+
+// CustomerIDFrom returns the customer ID set by WithCustomerID, if any.
+func CustomerIDFrom(ctx context.Context) string {
+	customerID, _ := ctx.Value(customerIDKey{}).(string)
+	return customerID
+}
+
+// RateLimit is the budget of a path family, per customer. A zero Rate disables the limit.
+type RateLimit struct {
+	// Rate is the number of requests per second.
+	Rate float64
+	// Burst is the number of requests allowed at once.
+	Burst int
+}
+
+// RateLimitsFromConfig returns the configured budget of every path family.
+func RateLimitsFromConfig() map[string]RateLimit {
+	burst := configs.GetRateLimitBurst()
+	return map[string]RateLimit{
+		APIFamily:              {Rate: configs.GetRateLimitAPI(), Burst: burst},
+		BackupRecoveryFamily:   {Rate: configs.GetRateLimitBackupRecovery(), Burst: burst},
+		DisasterRecoveryFamily: {Rate: configs.GetRateLimitDisasterRecovery(), Burst: burst},
+	}
+}
+
+// SharedRateLimiter returns the limiter shared by every rest client of the process, so the
+// budget of a customer holds across collections.
+func SharedRateLimiter() *RateLimiter {
+	sharedLimiterOnce.Do(func() {
+		sharedLimiter = NewRateLimiter(RateLimitsFromConfig())
+	})
+	return sharedLimiter
+}
+
+type bucketKey struct {
+	customerID, family string
+}
+
+// RateLimiter keeps a token bucket per customer and path family. Buckets slow down when the
+// API answers 429 and recover gradually on successful calls.
+type RateLimiter struct {
+	limits  map[string]RateLimit
+	mu      sync.Mutex
+	buckets map[bucketKey]*tokenBucket
+}
+
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: make(map[bucketKey]*tokenBucket),
+	}
+}
+
+// pathFamily returns the family of the request path, or an empty string when it is not limited.
+func pathFamily(path string) string {
+	for _, family := range []string{APIFamily, BackupRecoveryFamily, DisasterRecoveryFamily} {
+		if strings.HasPrefix(path, family) {
+			return family
+		}
+	}
+	return ""
+}
+
+func (l *RateLimiter) bucket(customerID, path string) (*tokenBucket, string) {
+	family := pathFamily(path)
+	limit, found := l.limits[family]
+	if !found || limit.Rate <= 0 {
+		return nil, family
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := bucketKey{customerID: customerID, family: family}
+	b, found := l.buckets[key]
+	if !found {
+		b = newTokenBucket(limit, time.Now())
+		l.buckets[key] = b
+	}
+	return b, family
+}
+
+// Wait blocks until the customer may send a request on path, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, customerID, path string) error {
+	b, family := l.bucket(customerID, path)
+	if b == nil {
+		return nil
+	}
+	wait := b.reserve(time.Now())
+	prometheus.RestRateLimitWait.WithLabelValues(family).Set(wait.Seconds())
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Observe adapts the customer's rate on path to the status code returned by the API.
+func (l *RateLimiter) Observe(customerID, path string, statusCode int) {
+	b, _ := l.bucket(customerID, path)
+	if b == nil {
+		return
+	}
+	if statusCode == http.StatusTooManyRequests {
+		b.slowDown(time.Now())
+		return
+	}
+	if statusCode < http.StatusBadRequest {
+		b.recover(time.Now())
+	}
+}
+
+// tokenBucket is refilled at rate tokens per second, up to burst tokens.
+type tokenBucket struct {
+	mu      sync.Mutex
+	rate    float64
+	maxRate float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	burst := math.Max(float64(limit.Burst), 1)
+	return &tokenBucket{rate: limit.Rate, maxRate: limit.Rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// reserve takes a token and returns how long the caller has to wait before using it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// slowDown halves the rate, down to minRateFraction of the configured one.
+func (b *tokenBucket) slowDown(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	b.rate = math.Max(b.rate/2, b.maxRate*minRateFraction)
+}
+
+// recover raises a slowed down rate back towards the configured one.
+func (b *tokenBucket) recover(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate < b.maxRate {
+		b.refill(now)
+		b.rate = math.Min(b.rate*recoveryFactor, b.maxRate)
+	}
+}
+
+func (b *tokenBucket) currentRate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rate
+}
+
+// do sends a single attempt of the request once the rate limiter lets it through.
+func (client *RestClient) do(ctx context.Context, request *http.Request) (*http.Response, error) {
+	if client.limiter == nil {
+		return client.client.Do(request)
+	}
+	customerID := CustomerIDFrom(ctx)
+	if err := client.limiter.Wait(ctx, customerID, request.URL.Path); err != nil {
+		return nil, err
+	}
+	response, err := client.client.Do(request)
+	if err == nil {
+		client.limiter.Observe(customerID, request.URL.Path, response.StatusCode)
+	}
+	return response, err
+}
@@ -0,0 +1,61 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+func TestPathFamily(t *testing.T) {
+	assert.Equal(t, APIFamily, pathFamily("/api/v1/virtual-machines"))
+	assert.Equal(t, BackupRecoveryFamily, pathFamily("/backup-recovery/v1beta1/protection-stores"))
+	assert.Equal(t, DisasterRecoveryFamily, pathFamily("/disaster-recovery/v1beta1/protected-vms"))
+	assert.Equal(t, "", pathFamily("/as/token.oauth2"))
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(RateLimit{Rate: 10, Burst: 2}, now)
+	assert.Zero(t, b.reserve(now))
+	assert.Zero(t, b.reserve(now))
+	assert.Equal(t, 100*time.Millisecond, b.reserve(now))
+	assert.Zero(t, b.reserve(now.Add(time.Second)))
+
+	for i := 0; i < 10; i++ {
+		b.slowDown(now)
+	}
+	assert.Equal(t, 1.0, b.currentRate())
+	b.recover(now)
+	assert.Equal(t, 1.05, b.currentRate())
+}
+
+func TestRateLimiterPerCustomer(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	limiter := NewRateLimiter(map[string]RateLimit{BackupRecoveryFamily: {Rate: 0.001, Burst: 1}})
+	ctx := context.Background()
+	path := "/backup-recovery/v1beta1/virtual-machines/vm1/backups"
+
+	assert.NoError(t, limiter.Wait(ctx, "customer1", path))
+	assert.NoError(t, limiter.Wait(ctx, "customer2", path))
+	assert.NoError(t, limiter.Wait(ctx, "customer1", "/api/v1/datastores"))
+
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(cancelled, "customer1", path), context.DeadlineExceeded)
+
+	b, _ := limiter.bucket("customer2", path)
+	limiter.Observe("customer2", path, http.StatusTooManyRequests)
+	assert.Less(t, b.currentRate(), 0.001)
+}
+
+func TestCustomerIDContext(t *testing.T) {
+	assert.Equal(t, "", CustomerIDFrom(context.Background()))
+	assert.Equal(t, "customer1", CustomerIDFrom(WithCustomerID(context.Background(), "customer1")))
+}
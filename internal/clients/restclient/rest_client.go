@@ -25,9 +25,17 @@ type RestInterface interface {
 type RestClient struct {
 	client  *http.Client
 	baseURL string
+	retry   RetryPolicy
+	limiter *RateLimiter
+	breaker *CircuitBreaker
 }
 
 func NewRestClient(hostName string, timeout time.Duration) (RestInterface, error) {
+	return NewRestClientWithRetryPolicy(hostName, timeout, RetryPolicyFromConfig())
+}
+
+// NewRestClientWithRetryPolicy creates a rest client retrying idempotent requests according to policy.
+func NewRestClientWithRetryPolicy(hostName string, timeout time.Duration, policy RetryPolicy) (RestInterface, error) {
 	tr := &http.Transport{
 		Proxy: func(req *http.Request) (*url.URL, error) {
 			return url.Parse("http://web-proxy.corp.hpecorp.net:8080")
@@ -39,6 +47,9 @@ func NewRestClient(hostName string, timeout time.Duration) (RestInterface, error
 	restClient := &RestClient{
 		client:  client,
 		baseURL: hostName,
+		retry:   policy,
+		limiter: SharedRateLimiter(),
+		breaker: SharedCircuitBreaker(),
 	}
 	return restClient, nil
 }
@@ -59,7 +70,17 @@ func (client *RestClient) SendRequest(ctx context.Context, request *http.Request
 	logger.WithContext(ctx).Debug("Request : ", request.Method, " ", request.URL.String())
 	request.Header.Set("Content-Type", "application/json")
 
-	response, err := client.client.Do(request)
+	endpoint := endpointLabel(request)
+	if client.breaker != nil {
+		if err = client.breaker.Allow(endpoint); err != nil {
+			logger.WithContext(ctx).Errorf("error in request : %v", err.Error())
+			return nil, err
+		}
+	}
+	response, err := client.sendWithRetry(ctx, request)
+	if client.breaker != nil {
+		client.breaker.Record(ctx, endpoint, response, err)
+	}
 	if err != nil {
 		logger.WithContext(ctx).Errorf("error in request : %v", err.Error())
 		return nil, err
@@ -0,0 +1,167 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package restclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+// Retry reasons, used as metric label
+const (
+	retryReasonNetwork     = "network"
+	retryReasonRateLimited = "rate_limited"
+	retryReasonServer      = "server_error"
+)
+
+const (
+	// maxRetryDelay caps the exponential backoff, a Retry-After header may still ask for longer.
+	maxRetryDelay = 30 * time.Second
+	// maxDrainBytes is read from a discarded response so the connection can be reused.
+	maxDrainBytes = 4096
+)
+
+// idPattern matches the asset IDs in a request path, see endpointLabel
+var idPattern = regexp.MustCompile(`[0-9a-fA-F]{8}(-?[0-9a-fA-F]{4}){3}-?[0-9a-fA-F]{12}|[0-9a-fA-F]{24,}`)
+
+// RetryPolicy controls how idempotent requests are retried on network errors, 429 and 5xx responses.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on every following retry.
+	BaseDelay time.Duration
+	// MaxElapsedTime bounds the time spent retrying a request, zero means no bound.
+	MaxElapsedTime time.Duration
+}
+
+// RetryPolicyFromConfig builds the retry policy from the rest settings.
+func RetryPolicyFromConfig() RetryPolicy {
+	baseDelay, err := time.ParseDuration(configs.GetRetryBackoff())
+	if err != nil {
+		logger.Errorf("Invalid retry backoff %q, retries are not delayed : %v", configs.GetRetryBackoff(), err)
+	}
+	return RetryPolicy{
+		MaxAttempts:    configs.GetRestMaxAttempts(),
+		BaseDelay:      baseDelay,
+		MaxElapsedTime: configs.GetRestMaxElapsedTime(),
+	}
+}
+
+// isIdempotent reports whether a request can safely be sent again.
+func isIdempotent(request *http.Request) bool {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return false
+	}
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+// retryReason returns why the outcome of an attempt should be retried, or an empty string.
+func retryReason(ctx context.Context, request *http.Request, response *http.Response, err error) string {
+	if err != nil {
+		if ctx.Err() != nil || request.Context().Err() != nil || errors.Is(err, context.Canceled) {
+			return ""
+		}
+		return retryReasonNetwork
+	}
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests:
+		return retryReasonRateLimited
+	case response.StatusCode >= http.StatusInternalServerError:
+		return retryReasonServer
+	}
+	return ""
+}
+
+// delay returns the wait before the given retry (1 for the first one): the Retry-After of the
+// response if any, else a full jitter exponential backoff.
+func (policy RetryPolicy) delay(retry int, response *http.Response) time.Duration {
+	if after, ok := retryAfter(response); ok {
+		return after
+	}
+	if policy.BaseDelay <= 0 {
+		return 0
+	}
+	backoff := policy.BaseDelay << (retry - 1)
+	if backoff <= 0 || backoff > maxRetryDelay {
+		backoff = maxRetryDelay
+	}
+	//nolint:gosec // jitter does not need a secure random source
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+// retryAfter parses the Retry-After header, in seconds or as an HTTP date.
+func retryAfter(response *http.Response) (time.Duration, bool) {
+	if response == nil {
+		return 0, false
+	}
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if after := time.Until(date); after > 0 {
+			return after, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// endpointLabel strips the asset IDs from the path to keep the metric cardinality bounded.
+func endpointLabel(request *http.Request) string {
+	return request.Method + " " + idPattern.ReplaceAllString(request.URL.Path, "{id}")
+}
+
+// sendWithRetry sends the request, retrying idempotent requests according to the client policy.
+func (client *RestClient) sendWithRetry(ctx context.Context, request *http.Request) (*http.Response, error) {
+	response, err := client.do(ctx, request)
+	if !isIdempotent(request) {
+		return response, err
+	}
+	start := time.Now()
+	for attempt := 1; attempt < client.retry.MaxAttempts; attempt++ {
+		reason := retryReason(ctx, request, response, err)
+		if reason == "" {
+			break
+		}
+		wait := client.retry.delay(attempt, response)
+		if client.retry.MaxElapsedTime > 0 && time.Since(start)+wait > client.retry.MaxElapsedTime {
+			logger.WithContext(ctx).Warnf("Giving up retrying %s after %v attempts", request.URL.Path, attempt)
+			break
+		}
+		if response != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxDrainBytes))
+			response.Body.Close()
+		}
+		logger.WithContext(ctx).Warnf("Retrying %s in %v, attempt %v, reason %s", request.URL.Path, wait,
+			attempt+1, reason)
+		prometheus.RestRetryCnt.WithLabelValues(endpointLabel(request), reason).Inc()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if request.GetBody != nil {
+			if request.Body, err = request.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		response, err = client.do(ctx, request)
+	}
+	return response, err
+}
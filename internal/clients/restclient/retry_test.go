@@ -0,0 +1,105 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+func newTestClient(url string, policy RetryPolicy) *RestClient {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	return &RestClient{client: &http.Client{Timeout: time.Second}, baseURL: url, retry: policy}
+}
+
+// failingServer answers the first failures requests with status, then 200.
+func failingServer(failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	return srv, &calls
+}
+
+func TestSendRequestRetriesGet(t *testing.T) {
+	srv, calls := failingServer(2, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+	client := newTestClient(srv.URL, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/virtual-machines", http.NoBody)
+	resp, err := client.SendRequest(context.Background(), req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestSendRequestStopsAfterMaxAttempts(t *testing.T) {
+	srv, calls := failingServer(5, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"0"}})
+	defer srv.Close()
+	client := newTestClient(srv.URL, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Hour})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/datastores", http.NoBody)
+	resp, err := client.SendRequest(context.Background(), req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestSendRequestHonorsMaxElapsedTime(t *testing.T) {
+	srv, calls := failingServer(5, http.StatusInternalServerError, http.Header{"Retry-After": []string{"10"}})
+	defer srv.Close()
+	client := newTestClient(srv.URL, RetryPolicy{MaxAttempts: 5, MaxElapsedTime: time.Second})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/datastores", http.NoBody)
+	resp, err := client.SendRequest(context.Background(), req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestSendRequestDoesNotRetryPost(t *testing.T) {
+	srv, calls := failingServer(1, http.StatusBadGateway, nil)
+	defer srv.Close()
+	client := newTestClient(srv.URL, RetryPolicy{MaxAttempts: 3})
+
+	req, _ := http.NewRequest(http.MethodPost, "/dummy", http.NoBody)
+	resp, err := client.SendRequest(context.Background(), req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second}
+	for retry := 1; retry < 10; retry++ {
+		delay := policy.delay(retry, nil)
+		assert.Greater(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, maxRetryDelay)
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	assert.Equal(t, 7*time.Second, policy.delay(1, resp))
+}
+
+func TestEndpointLabel(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet,
+		"/backup-recovery/v1beta1/virtual-machines/5d3c8f3e-4a2b-4c1d-9e8f-0a1b2c3d4e5f/backups?limit=50", http.NoBody)
+	assert.Equal(t, "GET /backup-recovery/v1beta1/virtual-machines/{id}/backups", endpointLabel(req))
+}
@@ -0,0 +1,121 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package sink
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+// Compression is the codec applied to the stored files, see configs.GetCompression.
+type Compression string
+
+const (
+	NoCompression   Compression = "none"
+	GzipCompression Compression = "gzip"
+	ZstdCompression Compression = "zstd"
+)
+
+var errUnknownCompression = errors.New("unknown compression")
+
+// CompressionFromConfig returns the configured compression.
+func CompressionFromConfig() (Compression, error) {
+	switch compression := Compression(configs.GetCompression()); compression {
+	case "", NoCompression:
+		return NoCompression, nil
+	case GzipCompression, ZstdCompression:
+		return compression, nil
+	default:
+		return "", fmt.Errorf("%w: %s", errUnknownCompression, compression)
+	}
+}
+
+// Extension returns the suffix appended to the keys of compressed files.
+func (c Compression) Extension() string {
+	switch c {
+	case GzipCompression:
+		return ".gz"
+	case ZstdCompression:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// ContentEncoding returns the HTTP Content-Encoding of compressed files.
+func (c Compression) ContentEncoding() string {
+	switch c {
+	case GzipCompression, ZstdCompression:
+		return string(c)
+	default:
+		return ""
+	}
+}
+
+// NewWriter returns a writer compressing to out. Closing it flushes the compressed stream
+// without closing out.
+func (c Compression) NewWriter(out io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case GzipCompression:
+		return gzip.NewWriter(out), nil
+	case ZstdCompression:
+		return zstd.NewWriter(out, zstd.WithEncoderConcurrency(1))
+	case "", NoCompression:
+		return nopWriteCloser{out}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownCompression, c)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// Compressed returns a sink compressing the content before storing it in s. Keys get the
+// extension of the codec and the stored size is the compressed one. Parquet files compress
+// their pages themselves and are stored as they are.
+func Compressed(s Sink, compression Compression) Sink {
+	if compression == "" || compression == NoCompression {
+		return s
+	}
+	return &compressedSink{Sink: s, compression: compression}
+}
+
+type compressedSink struct {
+	Sink
+	compression Compression
+}
+
+func (s *compressedSink) Put(ctx context.Context, key string, content io.Reader,
+	attributes Attributes) (Object, error) {
+	if attributes.ContentType == ParquetContentType {
+		return s.Sink.Put(ctx, key, content, attributes)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		compressor, err := s.compression.NewWriter(writer)
+		if err == nil {
+			_, err = io.Copy(compressor, content)
+			if closeErr := compressor.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		writer.CloseWithError(err)
+	}()
+	attributes.ContentEncoding = s.compression.ContentEncoding()
+	object, err := s.Sink.Put(ctx, key+s.compression.Extension(), reader, attributes)
+	// stop the compression when the store gave up before the end of the content
+	reader.CloseWithError(err)
+	return object, err
+}
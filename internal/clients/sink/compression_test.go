@@ -0,0 +1,94 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decompress(t *testing.T, compression Compression, content []byte) string {
+	var reader io.Reader
+	switch compression {
+	case GzipCompression:
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		require.NoError(t, err)
+		reader = gz
+	case ZstdCompression:
+		zr, err := zstd.NewReader(bytes.NewReader(content))
+		require.NoError(t, err)
+		defer zr.Close()
+		reader = zr
+	default:
+		reader = bytes.NewReader(content)
+	}
+	plain, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(plain)
+}
+
+func TestCompressedSink(t *testing.T) {
+	payload := strings.Repeat(`{"id":"vm-1","name":"vm"},`, 1000)
+	for _, test := range []struct {
+		compression Compression
+		key         string
+		encoding    string
+	}{
+		{NoCompression, "VM/a.json", ""},
+		{GzipCompression, "VM/a.json.gz", "gzip"},
+		{ZstdCompression, "VM/a.json.zst", "zstd"},
+	} {
+		t.Run(string(test.compression), func(t *testing.T) {
+			dir := t.TempDir()
+			local, err := NewLocalSink(dir)
+			require.NoError(t, err)
+			s := Compressed(local, test.compression)
+
+			object, err := s.Put(context.Background(), "VM/a.json", strings.NewReader(payload),
+				Attributes{ContentType: JSONContentType})
+			require.NoError(t, err)
+			assert.Equal(t, test.key, object.Key)
+
+			stored, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(test.key)))
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(stored)), object.Size)
+			assert.Equal(t, checksum(string(stored)), object.SHA256, "the checksum is the stored one")
+			assert.Equal(t, payload, decompress(t, test.compression, stored))
+			if test.compression != NoCompression {
+				assert.Less(t, object.Size, int64(len(payload)))
+			}
+			sidecar, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(test.key)+metadataSuffix))
+			require.NoError(t, err)
+			assert.Contains(t, string(sidecar), test.encoding)
+		})
+	}
+}
+
+func TestCompressedSinkStoreFailure(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocalSink(dir)
+	require.NoError(t, err)
+	_, err = Compressed(local, GzipCompression).Put(context.Background(), "../escape.json",
+		strings.NewReader("x"), Attributes{ContentType: JSONContentType})
+	assert.ErrorIs(t, err, errInvalidKey)
+}
+
+func TestCompressedSinkStoresParquetAsIs(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocalSink(dir)
+	require.NoError(t, err)
+	object, err := Compressed(local, ZstdCompression).Put(context.Background(), "VM/a.parquet",
+		strings.NewReader("PAR1"), Attributes{ContentType: ParquetContentType})
+	require.NoError(t, err)
+	assert.Equal(t, Object{Key: "VM/a.parquet", Size: 4, SHA256: checksum("PAR1")}, object)
+}
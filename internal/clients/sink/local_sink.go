@@ -0,0 +1,87 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package sink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// metadataSuffix is appended to a key for the file holding its content type and metadata.
+const metadataSuffix = ".metadata.json"
+
+var (
+	errInvalidKey = errors.New("key escapes the sink directory")
+
+	json = jsoniter.ConfigCompatibleWithStandardLibrary
+)
+
+// LocalSink stores the files under a local directory, for offline runs in dev and CI. The
+// attributes of a key are written next to it in <key>.metadata.json.
+type LocalSink struct {
+	dir string
+}
+
+type localMetadata struct {
+	ContentType     string            `json:"contentType"`
+	ContentEncoding string            `json:"contentEncoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+func NewLocalSink(dir string) (*LocalSink, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalSink{dir: dir}, nil
+}
+
+func (s *LocalSink) Location() string {
+	return s.dir
+}
+
+func (s *LocalSink) Put(ctx context.Context, key string, content io.Reader, attributes Attributes) (Object, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return Object{}, errInvalidKey
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return Object{}, err
+	}
+	// write to a temporary file first so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return Object{}, err
+	}
+	defer os.Remove(tmp.Name())
+	body := newDigestReader(content)
+	_, err = io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logger.WithContext(ctx).Errorf("Writing %s failed : %v", path, err)
+		return Object{}, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return Object{}, err
+	}
+
+	sidecar, err := json.Marshal(localMetadata{
+		ContentType:     attributes.ContentType,
+		ContentEncoding: attributes.ContentEncoding,
+		Metadata:        attributes.Metadata,
+	})
+	if err != nil {
+		return Object{}, err
+	}
+	if err = os.WriteFile(path+metadataSuffix, sidecar, 0o600); err != nil {
+		return Object{}, err
+	}
+	return body.object(key), nil
+}
@@ -0,0 +1,88 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package sink
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3upload "github.hpe.com/nimble-dcs/panorama-s3-upload/pkg/s3"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/secrets"
+)
+
+// S3Options configure an S3Sink.
+type S3Options struct {
+	// Bucket is the bucket name or URL, as found in the service discovery config map.
+	Bucket string
+	Region string
+	// Endpoint is the URL of an S3-compatible service such as MinIO, empty for AWS S3.
+	Endpoint string
+	// PathStyle addresses the bucket in the URL path rather than in the host name.
+	PathStyle bool
+}
+
+// S3Sink stores the files in an AWS S3 or S3-compatible bucket, using multipart uploads so
+// content of unknown length can be streamed.
+type S3Sink struct {
+	bucketURL string
+	bucket    string
+	uploader  *manager.Uploader
+}
+
+func NewS3Sink(ctx context.Context, options S3Options) (*S3Sink, error) {
+	loadOptions := []func(*config.LoadOptions) error{config.WithRegion(options.Region)}
+	if secrets.GetOrEmpty(ctx, secrets.AWSAccessKeyID) != "" {
+		// read the keys on every refresh so rotated secrets are picked up
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(aws.NewCredentialsCache(
+			aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+				return aws.Credentials{
+					AccessKeyID:     secrets.GetOrEmpty(ctx, secrets.AWSAccessKeyID),
+					SecretAccessKey: secrets.GetOrEmpty(ctx, secrets.AWSSecretAccessKey),
+					Source:          "SecretsProvider",
+				}, nil
+			}))))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if options.Endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFromURL(options.Endpoint)
+		}
+		o.UsePathStyle = options.PathStyle
+	})
+	return &S3Sink{
+		bucketURL: options.Bucket,
+		bucket:    s3upload.GetS3BucketFromURL(options.Bucket),
+		uploader:  manager.NewUploader(client),
+	}, nil
+}
+
+func (s *S3Sink) Location() string {
+	return s.bucketURL
+}
+
+func (s *S3Sink) Put(ctx context.Context, key string, content io.Reader, attributes Attributes) (Object, error) {
+	body := newDigestReader(content)
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(attributes.ContentType),
+		Metadata:    attributes.Metadata,
+	}
+	if attributes.ContentEncoding != "" {
+		input.ContentEncoding = aws.String(attributes.ContentEncoding)
+	}
+	if _, err := s.uploader.Upload(ctx, input); err != nil {
+		logger.WithContext(ctx).Errorf("Upload of %s to %s failed : %v", key, s.bucket, err)
+		return Object{}, err
+	}
+	return body.object(key), nil
+}
@@ -0,0 +1,140 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package sink
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/logging"
+)
+
+// Sink types, see configs.GetSinkType
+const (
+	S3Type           = "s3"
+	S3CompatibleType = "s3-compatible"
+	LocalType        = "local"
+)
+
+// Content types of the stored files
+const (
+	JSONContentType    = "application/json"
+	NDJSONContentType  = "application/x-ndjson"
+	ParquetContentType = "application/vnd.apache.parquet"
+)
+
+var (
+	errUnknownSink     = errors.New("unknown sink type")
+	errMissingEndpoint = errors.New("an endpoint is mandatory for an s3-compatible sink")
+
+	logger = logging.GetLogger()
+
+	defaultSink     Sink
+	defaultSinkErr  error
+	defaultSinkOnce sync.Once
+)
+
+// Attributes describe the content of a stored file.
+type Attributes struct {
+	ContentType string
+	// ContentEncoding is set by the compressing sink, see Compressed.
+	ContentEncoding string
+	Metadata        map[string]string
+}
+
+// Object is a stored file.
+type Object struct {
+	// Key may differ from the requested one, a compressing sink appends the encoding suffix.
+	Key string
+	// Size is the number of bytes stored, after compression.
+	Size int64
+	// SHA256 is the hex encoded checksum of the bytes stored.
+	SHA256 string
+}
+
+// Sink stores the files produced by a collection.
+type Sink interface {
+	// Put stores content under key.
+	Put(ctx context.Context, key string, content io.Reader, attributes Attributes) (Object, error)
+	// Location returns the bucket or directory the keys are stored in.
+	Location() string
+}
+
+// FromConfig builds the sink selected by configuration, compressing with the configured codec.
+func FromConfig(ctx context.Context) (Sink, error) {
+	compression, err := CompressionFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	s, err := storeFromConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Compressed(s, compression), nil
+}
+
+func storeFromConfig(ctx context.Context) (Sink, error) {
+	switch sinkType := configs.GetSinkType(); sinkType {
+	case S3Type:
+		return NewS3Sink(ctx, S3Options{
+			Bucket: configs.GetAWSS3BucketName(),
+			Region: configs.GetAWSRegion(),
+		})
+	case S3CompatibleType:
+		if configs.GetSinkEndpoint() == "" {
+			return nil, errMissingEndpoint
+		}
+		return NewS3Sink(ctx, S3Options{
+			Bucket:    configs.GetAWSS3BucketName(),
+			Region:    configs.GetAWSRegion(),
+			Endpoint:  configs.GetSinkEndpoint(),
+			PathStyle: configs.GetSinkPathStyle(),
+		})
+	case LocalType:
+		return NewLocalSink(configs.GetSinkLocalDir())
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownSink, sinkType)
+	}
+}
+
+// Default returns the sink shared by every collection of the process, built from configuration
+// on first use.
+func Default(ctx context.Context) (Sink, error) {
+	defaultSinkOnce.Do(func() {
+		defaultSink, defaultSinkErr = FromConfig(ctx)
+		if defaultSinkErr != nil {
+			logger.WithContext(ctx).Errorf("Sink creation failed : %v", defaultSinkErr)
+		}
+	})
+	return defaultSink, defaultSinkErr
+}
+
+// digestReader counts and checksums the bytes read from the wrapped reader.
+type digestReader struct {
+	reader io.Reader
+	count  int64
+	hash   hash.Hash
+}
+
+func newDigestReader(reader io.Reader) *digestReader {
+	return &digestReader{reader: reader, hash: sha256.New()}
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.reader.Read(p)
+	d.count += int64(n)
+	d.hash.Write(p[:n])
+	return n, err
+}
+
+// object returns the stored object once the whole content was read.
+func (d *digestReader) object(key string) Object {
+	return Object{Key: key, Size: d.count, SHA256: hex.EncodeToString(d.hash.Sum(nil))}
+}
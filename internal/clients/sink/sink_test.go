@@ -0,0 +1,120 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package sink
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLocalSinkPut(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocalSink(dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir, s.Location())
+
+	object, err := s.Put(context.Background(), "VM/partitionKey=1/a.json", strings.NewReader(`{"a":1}`),
+		Attributes{ContentType: JSONContentType, Metadata: map[string]string{"collection": "c1"}})
+	require.NoError(t, err)
+	assert.Equal(t, Object{Key: "VM/partitionKey=1/a.json", Size: 7, SHA256: checksum(`{"a":1}`)}, object)
+
+	content, err := os.ReadFile(filepath.Join(dir, "VM", "partitionKey=1", "a.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(content))
+	sidecar, err := os.ReadFile(filepath.Join(dir, "VM", "partitionKey=1", "a.json"+metadataSuffix))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"contentType":"application/json","metadata":{"collection":"c1"}}`, string(sidecar))
+
+	_, err = s.Put(context.Background(), "../escape.json", strings.NewReader("x"),
+		Attributes{ContentType: JSONContentType})
+	assert.ErrorIs(t, err, errInvalidKey)
+}
+
+// fakeS3 records the objects put on an S3-compatible endpoint.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	headers map[string]http.Header
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[r.URL.Path] = string(body)
+	f.headers[r.URL.Path] = r.Header.Clone()
+	w.Header().Set("ETag", `"etag"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestS3CompatibleSinkPut(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	fake := &fakeS3{objects: make(map[string]string), headers: make(map[string]http.Header)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s, err := NewS3Sink(context.Background(), S3Options{
+		Bucket:    "https://hauler-bucket.s3.amazonaws.com",
+		Region:    "us-west-2",
+		Endpoint:  server.URL,
+		PathStyle: true,
+	})
+	require.NoError(t, err)
+	object, err := s.Put(context.Background(), "VM/a.json", strings.NewReader(`[1,2]`),
+		Attributes{ContentType: JSONContentType, ContentEncoding: "gzip",
+			Metadata: map[string]string{"collection": "c1"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), object.Size)
+	assert.Equal(t, checksum(`[1,2]`), object.SHA256)
+	path := "/" + s.bucket + "/VM/a.json"
+	assert.Equal(t, `[1,2]`, fake.objects[path])
+	assert.Equal(t, JSONContentType, fake.headers[path].Get("Content-Type"))
+	assert.Equal(t, "c1", fake.headers[path].Get("X-Amz-Meta-Collection"))
+	assert.Equal(t, "gzip", fake.headers[path].Get("Content-Encoding"))
+}
+
+func TestFromConfig(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("sink_type", LocalType)
+	viper.Set("sink_local_dir", t.TempDir())
+	s, err := FromConfig(context.Background())
+	require.NoError(t, err)
+	assert.IsType(t, &LocalSink{}, s)
+
+	viper.Set("compression", string(ZstdCompression))
+	s, err = FromConfig(context.Background())
+	require.NoError(t, err)
+	assert.IsType(t, &compressedSink{}, s)
+
+	viper.Set("compression", "lz4")
+	_, err = FromConfig(context.Background())
+	assert.ErrorIs(t, err, errUnknownCompression)
+	viper.Set("compression", string(NoCompression))
+
+	viper.Set("sink_type", S3CompatibleType)
+	_, err = FromConfig(context.Background())
+	assert.ErrorIs(t, err, errMissingEndpoint)
+
+	viper.Set("sink_type", "ftp")
+	_, err = FromConfig(context.Background())
+	assert.ErrorIs(t, err, errUnknownSink)
+}
@@ -4,8 +4,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -52,6 +53,25 @@ const (
 	maxNumPaddingBytesInJWT        = 4
 )
 
+// Output formats of the asset files, see ConsumerDetails.OutputFormat
+const (
+	JSONFormat    = "json"
+	NDJSONFormat  = "ndjson"
+	ParquetFormat = "parquet"
+)
+
+// OutputFormats are the supported output formats.
+var OutputFormats = []string{JSONFormat, NDJSONFormat, ParquetFormat}
+
+// FileType returns the Harmony file type of fileType files written in format. Files in other
+// formats than JSON get the format as suffix, e.g. VM-NDJSON or VM-PARQUET.
+func FileType(fileType, format string) string {
+	if format == "" || format == JSONFormat {
+		return fileType
+	}
+	return fileType + "-" + strings.ToUpper(format)
+}
+
 func MapPFHErrorsToGRPCErrors(err error) (statusCode codes.Code, statusMsg string) {
 	return MapPFHErrorsToGRPCErrorsWithContext(context.Background(), err)
 }
@@ -105,6 +125,15 @@ func MapPFHErrorsToRESTErrors(err error) (statusCode int, statusMsg string) {
 }
 
 func MapPFHErrorsToRESTErrorsWithContext(ctx context.Context, err error) (statusCode int, statusMsg string) {
+	// API failures may be wrapped on their way up from the rest client
+	var restErr utils.RestClientError
+	if errors.As(err, &restErr) {
+		return restErr.StatusCode, restErr.Error()
+	}
+	var circuitErr utils.CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		return http.StatusServiceUnavailable, circuitErr.Error()
+	}
 	switch err := err.(type) {
 	case utils.NotFoundError:
 		return http.StatusNotFound, utils.NotFoundErrorMsg
@@ -136,7 +165,7 @@ func MapPFHErrorsToRESTErrorsWithContext(ctx context.Context, err error) (status
 
 type ConsumerDetails struct {
 	PlatformCustomerID, CollectionID, Region, ApplicationCustomerID, ApplicationInstanceID,
-	CollectionTrigger, CollectionType string
+	CollectionTrigger, CollectionType, OutputFormat string
 }
 
 type DeviceFailedCollection struct {
@@ -404,13 +433,14 @@ func ClearMainMapEntries(mainVolumeCollectionMap2, mainStoragePoolMap2, mainVolu
 	ClearErrorMap(mainErrorMap2)
 }
 
-func PublishtoHarmonyKafka(ctx context.Context, consumerDetails ConsumerDetails, awsKey, bucketName string,
-	fileSize int, harmonyProducer producer.Producer) {
+// PublishtoHarmonyKafka announces a stored file on the Harmony topic and returns the publishing error.
+func PublishtoHarmonyKafka(ctx context.Context, consumerDetails ConsumerDetails, fileType, awsKey, bucketName string,
+	fileSize int, harmonyProducer producer.Producer) error {
 	res := model.HarmonyStatusRequest{}
 	res.Source.S3.Bucket = s3.GetS3BucketFromURL(bucketName)
 	res.Source.S3.Key = awsKey
 	res.Notification.FileDomain = configs.GetFileDomain()
-	res.Notification.FileType = configs.GetSourceType()
+	res.Notification.FileType = fileType
 	res.Notification.FileSize = fileSize
 	res.Notification.EntityID = consumerDetails.CollectionID + "_" + time.Now().UTC().Format(time.RFC3339)
 	res.Notification.FileName = awsKey
@@ -424,12 +454,15 @@ func PublishtoHarmonyKafka(ctx context.Context, consumerDetails ConsumerDetails,
 	if err != nil {
 		logger.WithContext(ctx).Errorf("Publishing kafka message failed from hauler to topic %v",
 			configs.GetHaulerHarmonyKafkaTopic())
-	} else {
-		logger.WithContext(ctx).Infof("Successfully published to topic %v",
-			configs.GetHaulerHarmonyKafkaTopic())
 		prometheus.KafkaProducerEventsCnt.WithLabelValues(configs.GetHaulerHarmonyKafkaTopic(),
-			UnknownDeviceType, Success, Success, EndOfCollection).Inc()
+			UnknownDeviceType, Success, Failed, EndOfCollection).Inc()
+		return err
 	}
+	logger.WithContext(ctx).Infof("Successfully published to topic %v",
+		configs.GetHaulerHarmonyKafkaTopic())
+	prometheus.KafkaProducerEventsCnt.WithLabelValues(configs.GetHaulerHarmonyKafkaTopic(),
+		UnknownDeviceType, Success, Success, EndOfCollection).Inc()
+	return nil
 }
 
 func PublishtoCommonKafka(ctx context.Context, consumerDetails ConsumerDetails, awsKey, bucketName string,
@@ -493,96 +526,6 @@ func PublishtoSchedulerKafka(ctx context.Context, consumerDetails ConsumerDetail
 	}
 }
 
-var UploadToS3 = func(jsonContent *[]byte, bucketName, awsS3Region, awsAccessKeyID, awsSecretAccessKey string,
-	uploadType s3.UploadType) (string, int, error) {
-	keyname, filesize, err := s3.UploadToAwsS3(jsonContent, bucketName, awsS3Region, awsAccessKeyID,
-		awsSecretAccessKey, s3.UploadType(configs.GetSourceType()))
-	return keyname, filesize, err
-}
-
-func UploadFileToServer(ctx context.Context, jsonContent []byte, consumerDetails ConsumerDetails, haulerType,
-	deviceType string, statusMap map[string]string, errorMap map[string]map[string]string,
-	schedulerProducer producer.Producer, harmonyProducer producer.Producer) {
-	var errors string
-	var uploadStatus = Success
-	var collectionStatus string
-	if len(errorMap) == 0 {
-		collectionStatus = Success
-		errors = ""
-	} else if v, found := errorMap[GRPCError]; found {
-		collectionStatus = Failed
-		errors = v[GRPCError]
-	} else if v, found := errorMap[RESTError]; found {
-		collectionStatus = Failed
-		errors = v[RESTError]
-	} else if v, found := errorMap[AuthError]; found {
-		collectionStatus = Failed
-		errors = v[AuthError]
-	} else if _, found := errorMap[Systems]; found {
-		collectionStatus = Failed
-		bs, _ := json.Marshal(errorMap)
-		errors = string(bs)
-	} else {
-		collectionStatus = Partial
-		bs, _ := json.Marshal(errorMap)
-		errors = string(bs)
-	}
-
-	requestStartTime := time.Now()
-	keyName, filesize, err := UploadToS3(&jsonContent, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), s3.UploadType(configs.GetSourceType()))
-	if err != nil {
-		logger.WithContext(ctx).Errorf("File upload failed with error %v", err)
-		uploadStatus = Failed
-	}
-	logger.WithContext(ctx).Infof("CollectionType %v  CollectionID  %v CollectionStatus %v UploadStatus %v",
-		consumerDetails.CollectionType, consumerDetails.CollectionID, collectionStatus, uploadStatus)
-	elapsed := time.Since(requestStartTime).Seconds()
-	prometheus.AwsRequestDuration.WithLabelValues(deviceType, collectionStatus, uploadStatus).Observe(elapsed)
-	if uploadStatus != Failed {
-		PublishtoHarmonyKafka(ctx, consumerDetails, keyName, configs.GetAWSS3BucketName(), filesize, harmonyProducer)
-		prometheus.UploadFileSize.WithLabelValues(consumerDetails.CollectionType, deviceType).Observe(float64(filesize))
-	}
-	statusMap[deviceType] = uploadStatus
-	PublishtoSchedulerKafka(ctx, consumerDetails, haulerType, uploadStatus, collectionStatus, deviceType,
-		strconv.Itoa(filesize), keyName, errors, schedulerProducer)
-}
-
-func UploadToServer(ctx context.Context, jsonContent []byte, consumerDetails ConsumerDetails, haulerType string, errorMap map[string]map[string]string,
-	schedulerProducer producer.Producer, harmonyProducer producer.Producer) {
-	var errors string
-	var uploadStatus = Success
-	var collectionStatus string
-	if len(errorMap) == 0 {
-		collectionStatus = Success
-		errors = ""
-	} else if v, found := errorMap[RESTError]; found {
-		collectionStatus = Failed
-		errors = v[RESTError]
-	} else if v, found := errorMap[AuthError]; found {
-		collectionStatus = Failed
-		errors = v[AuthError]
-	} else {
-		collectionStatus = Partial
-		bs, _ := json.Marshal(errorMap)
-		errors = string(bs)
-	}
-
-	keyName, filesize, err := UploadToS3(&jsonContent, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), s3.UploadType(configs.GetSourceType()))
-	if err != nil {
-		logger.WithContext(ctx).Errorf("File upload failed with error %v", err)
-		uploadStatus = Failed
-	}
-	logger.WithContext(ctx).Infof("CollectionType %v  CollectionID  %v CollectionStatus %v UploadStatus %v Error %v",
-		consumerDetails.CollectionType, consumerDetails.CollectionID, collectionStatus, uploadStatus, errors)
-	if uploadStatus != Failed {
-		PublishtoCommonKafka(ctx, consumerDetails, keyName, configs.GetAWSS3BucketName(), filesize, harmonyProducer)
-	}
-	// PublishtoSchedulerKafka(ctx, consumerDetails, haulerType, uploadStatus, collectionStatus, deviceType,
-	// 	strconv.Itoa(filesize), keyName, errors, schedulerProducer)
-}
-
 func Unique(s []string) []string {
 	inResult := make(map[string]bool)
 	var result []string
@@ -8,6 +8,7 @@ import (
 
 	"github.com/segmentio/kafka-go"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/auth"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/logging"
 )
 
@@ -25,16 +26,22 @@ type Handler interface {
 
 type handlerImpl struct {
 	dialer *kafka.Dialer
+	// err reports invalid Kafka authentication settings
+	err error
 }
 
+// NewKafkaHealthHandler returns the handler dialing the bootstrap servers with the TLS and SASL
+// settings of the consumer and producers.
 func NewKafkaHealthHandler() Handler {
-	return &handlerImpl{dialer: &kafka.Dialer{
-		Timeout:   10 * time.Second,
-		DualStack: true,
-	}}
+	security, err := auth.SettingsFromConfig(context.Background())
+	return &handlerImpl{dialer: security.Dialer(10*time.Second, true), err: err}
 }
 
 func (hh *handlerImpl) HandleHealth() error {
+	if hh.err != nil {
+		logger.Errorf("Invalid kafka authentication settings %v", hh.err)
+		return hh.err
+	}
 	// Check whether required kafka server is reachable
 	bootstrapServersSlice := strings.Split(configs.GetKafkaBootstrapServers(), `,`)
 	var err error
@@ -10,8 +10,16 @@ import (
 var KafkaDuration *prometheus.HistogramVec
 var KafkaConsumerEventsCnt *prometheus.CounterVec
 var KafkaProducerEventsCnt *prometheus.CounterVec
+var KafkaDeadLetterCnt *prometheus.CounterVec
+var KafkaConsumerLag *prometheus.GaugeVec
+var KafkaConsumerBusyWorkers *prometheus.GaugeVec
+var KafkaDuplicateCnt *prometheus.CounterVec
+var KafkaRetryCnt *prometheus.CounterVec
 var UploadFileSize *prometheus.SummaryVec
 var AwsRequestDuration *prometheus.HistogramVec
+var RestRetryCnt *prometheus.CounterVec
+var RestRateLimitWait *prometheus.GaugeVec
+var RestCircuitBreakerState *prometheus.GaugeVec
 
 type MetricCollectionHandler interface {
 	SetupMetricCollector()
@@ -21,8 +29,16 @@ type MetricCollectionHandlerImpl struct {
 	kafkaDuration                *prometheus.HistogramVec
 	kafkaConsumerEventsCnt       *prometheus.CounterVec
 	kafkaProducerEventsCnt       *prometheus.CounterVec
+	kafkaDeadLetterCnt           *prometheus.CounterVec
+	kafkaConsumerLag             *prometheus.GaugeVec
+	kafkaConsumerBusyWorkers     *prometheus.GaugeVec
+	kafkaDuplicateCnt            *prometheus.CounterVec
+	kafkaRetryCnt                *prometheus.CounterVec
 	uploadFileSize               *prometheus.SummaryVec
 	awsRequestProcessingDuration *prometheus.HistogramVec
+	restRetryCnt                 *prometheus.CounterVec
+	restRateLimitWait            *prometheus.GaugeVec
+	restCircuitBreakerState      *prometheus.GaugeVec
 }
 
 func NewMetricCollectionHandler() MetricCollectionHandler {
@@ -64,6 +80,61 @@ func (mch *MetricCollectionHandlerImpl) SetupMetricCollector() {
 		KafkaProducerEventsCnt = mch.kafkaProducerEventsCnt
 	}
 
+	if KafkaDeadLetterCnt == nil {
+		mch.kafkaDeadLetterCnt = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "kafka_dead_letter_count",
+			Help:      "Number of collection requests rejected, per reason and dead-letter forwarding status",
+		},
+			[]string{"topic", "reason", "status"})
+
+		KafkaDeadLetterCnt = mch.kafkaDeadLetterCnt
+	}
+
+	if KafkaConsumerLag == nil {
+		mch.kafkaConsumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "kafka_consumer_lag",
+			Help:      "Number of messages of a partition not committed yet by the consumer",
+		},
+			[]string{"topic", "partition"})
+
+		KafkaConsumerLag = mch.kafkaConsumerLag
+	}
+
+	if KafkaConsumerBusyWorkers == nil {
+		mch.kafkaConsumerBusyWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "kafka_consumer_busy_workers",
+			Help:      "Number of collection requests being processed by the consumer",
+		},
+			[]string{"topic"})
+
+		KafkaConsumerBusyWorkers = mch.kafkaConsumerBusyWorkers
+	}
+
+	if KafkaDuplicateCnt == nil {
+		mch.kafkaDuplicateCnt = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "kafka_duplicate_events_count",
+			Help:      "Number of collection requests received again for a running or completed collection",
+		},
+			[]string{"topic", "state"})
+
+		KafkaDuplicateCnt = mch.kafkaDuplicateCnt
+	}
+
+	if KafkaRetryCnt == nil {
+		mch.kafkaRetryCnt = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "kafka_retry_events_count",
+			Help:      "Number of failed collections re-enqueued to the retry topic or out of attempts",
+		},
+			[]string{"topic", "status"})
+
+		KafkaRetryCnt = mch.kafkaRetryCnt
+	}
+
 	if UploadFileSize == nil {
 		mch.uploadFileSize = prometheus.NewSummaryVec(prometheus.SummaryOpts{
 			Subsystem: constants.PrometheusMetrics,
@@ -86,4 +157,37 @@ func (mch *MetricCollectionHandlerImpl) SetupMetricCollector() {
 
 		AwsRequestDuration = mch.awsRequestProcessingDuration
 	}
+
+	if RestRetryCnt == nil {
+		mch.restRetryCnt = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "rest_retries_count",
+			Help:      "Number of REST requests retried, per endpoint and reason",
+		},
+			[]string{"endpoint", "reason"})
+
+		RestRetryCnt = mch.restRetryCnt
+	}
+
+	if RestRateLimitWait == nil {
+		mch.restRateLimitWait = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "rest_rate_limit_wait_seconds",
+			Help:      "Current wait in seconds imposed by the client side rate limiter, per API path family",
+		},
+			[]string{"family"})
+
+		RestRateLimitWait = mch.restRateLimitWait
+	}
+
+	if RestCircuitBreakerState == nil {
+		mch.restCircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Subsystem: constants.PrometheusMetrics,
+			Name:      "rest_circuit_breaker_state",
+			Help:      "State of the circuit breaker per REST endpoint: 0 closed, 1 half-open, 2 open",
+		},
+			[]string{"endpoint"})
+
+		RestCircuitBreakerState = mch.restCircuitBreakerState
+	}
 }
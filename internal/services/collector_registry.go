@@ -0,0 +1,144 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+)
+
+var (
+	errEmptyCollector     = errors.New("collector name, prefix and fetch function are mandatory")
+	errDuplicateCollector = errors.New("collector already registered")
+	errUnknownParent      = errors.New("parent collector is not registered")
+)
+
+// CollectorResult is the outcome of running a single Collector for a customer.
+type CollectorResult struct {
+	// Count is the number of assets written to FetchRequest.Output.
+	Count int
+	// IDs are the asset IDs handed to the collectors depending on this one.
+	IDs []string
+	// Errors holds the failures keyed by asset ID, or by the collector name when
+	// the whole asset type failed. They are merged into the collection error map.
+	Errors map[string]string
+}
+
+// FetchRequest carries everything a collector needs to fetch its assets for one collection.
+type FetchRequest struct {
+	Client     commonclient.AssetInterface
+	AuthHeader string
+	// ParentIDs are the IDs returned by the collector named in Collector.Parent,
+	// nil for top level collectors.
+	ParentIDs []string
+	// FanOut bounds the per asset requests issued for the customer.
+	FanOut *FanOutExecutor
+	// Output receives the document of the assets as pages are fetched, and is uploaded under
+	// the collector's prefix. Nothing is uploaded when the collector writes nothing or reports
+	// the whole asset type as failed.
+	Output io.Writer
+	// Format is the output format of the collection, one of handlers.OutputFormats.
+	Format string
+}
+
+// WriterFor returns the writer encoding assets of type T to req.Output in the requested format.
+// Grouped assets are written as an object keyed by parent asset ID, unless the format is NDJSON
+// or Parquet. The Parquet schema is derived from T.
+func WriterFor[T any](req FetchRequest, grouped bool) *StreamWriter {
+	switch {
+	case req.Format == handlers.NDJSONFormat:
+		return NewNDJSONWriter(req.Output)
+	case req.Format == handlers.ParquetFormat:
+		return NewParquetWriter(req.Output, reflect.TypeOf((*T)(nil)).Elem())
+	case grouped:
+		return NewJSONObjectWriter(req.Output)
+	default:
+		return NewJSONArrayWriter(req.Output)
+	}
+}
+
+// FetchFunc fetches the assets of one collector.
+type FetchFunc func(ctx context.Context, req FetchRequest) CollectorResult
+
+// Collector is a pluggable unit collecting a single asset type.
+type Collector struct {
+	// Name is the key used for the asset type in the collection error map, e.g. "VirtualMachines".
+	Name string
+	// Prefix is the S3 object prefix of the asset type, e.g. "VM" or "MSSQL-SNP".
+	Prefix string
+	// Parent is the name of the collector whose assets are expanded by this one.
+	Parent string
+	Fetch  FetchFunc
+}
+
+// CollectorReport is the uniform per collector summary of a collection run.
+type CollectorReport struct {
+	Name     string
+	Prefix   string
+	Key      string
+	Count    int
+	FileSize int
+	// SHA256 is the checksum of the uploaded file.
+	SHA256 string
+	Errors map[string]string
+	// Err is set when the assets could not be encoded or uploaded.
+	Err error
+}
+
+// CollectorRegistry holds the collectors run for every collection, in registration order.
+type CollectorRegistry struct {
+	collectors []Collector
+	index      map[string]int
+}
+
+func NewCollectorRegistry() *CollectorRegistry {
+	return &CollectorRegistry{index: make(map[string]int)}
+}
+
+// Register adds a collector to the registry. A collector depending on another one
+// must be registered after its parent.
+func (r *CollectorRegistry) Register(collector Collector) error {
+	if collector.Name == "" || collector.Prefix == "" || collector.Fetch == nil {
+		return errEmptyCollector
+	}
+	if _, found := r.index[collector.Name]; found {
+		return fmt.Errorf("%w: %s", errDuplicateCollector, collector.Name)
+	}
+	if collector.Parent != "" {
+		if _, found := r.index[collector.Parent]; !found {
+			return fmt.Errorf("%w: %s", errUnknownParent, collector.Parent)
+		}
+	}
+	r.index[collector.Name] = len(r.collectors)
+	r.collectors = append(r.collectors, collector)
+	return nil
+}
+
+// MustRegister registers the collectors and panics on the first invalid one.
+func (r *CollectorRegistry) MustRegister(collectors ...Collector) {
+	for _, collector := range collectors {
+		if err := r.Register(collector); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Collectors returns the registered collectors in registration order.
+func (r *CollectorRegistry) Collectors() []Collector {
+	return append([]Collector(nil), r.collectors...)
+}
+
+// Get returns the collector registered under name.
+func (r *CollectorRegistry) Get(name string) (Collector, bool) {
+	i, found := r.index[name]
+	if !found {
+		return Collector{}, false
+	}
+	return r.collectors[i], true
+}
@@ -0,0 +1,149 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils"
+)
+
+func noopFetch(context.Context, FetchRequest) CollectorResult {
+	return CollectorResult{}
+}
+
+func TestCollectorRegistryRegister(t *testing.T) {
+	registry := NewCollectorRegistry()
+	assert.ErrorIs(t, registry.Register(Collector{Name: "A", Prefix: "A"}), errEmptyCollector)
+	assert.NoError(t, registry.Register(Collector{Name: "A", Prefix: "A", Fetch: noopFetch}))
+	assert.ErrorIs(t, registry.Register(Collector{Name: "A", Prefix: "B", Fetch: noopFetch}), errDuplicateCollector)
+	assert.ErrorIs(t, registry.Register(Collector{Name: "B", Prefix: "B", Parent: "C", Fetch: noopFetch}),
+		errUnknownParent)
+	assert.NoError(t, registry.Register(Collector{Name: "B", Prefix: "B", Parent: "A", Fetch: noopFetch}))
+
+	collectors := registry.Collectors()
+	assert.Len(t, collectors, 2)
+	assert.Equal(t, "A", collectors[0].Name)
+	assert.Equal(t, "B", collectors[1].Name)
+	b, found := registry.Get("B")
+	assert.True(t, found)
+	assert.Equal(t, "A", b.Parent)
+	_, found = registry.Get("C")
+	assert.False(t, found)
+}
+
+func TestDefaultCollectorRegistry(t *testing.T) {
+	registry := DefaultCollectorRegistry()
+	prefixes := make(map[string]bool)
+	for _, collector := range registry.Collectors() {
+		assert.False(t, prefixes[collector.Prefix], "duplicate prefix %s", collector.Prefix)
+		prefixes[collector.Prefix] = true
+	}
+	assert.Len(t, prefixes, 22)
+
+	for name, parent := range map[string]string{
+		VMBackups: VirtualMachines, VMSnapshots: VirtualMachines,
+		DatastoreBackups: Datastores, DatastoreSnapshots: Datastores,
+		MssqlBackups: MssqlDB, MssqlSnapshots: MssqlDB,
+	} {
+		collector, found := registry.Get(name)
+		assert.True(t, found)
+		assert.Equal(t, parent, collector.Parent)
+	}
+}
+
+func TestCollectionError(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "boom", collectionError(ctx, errors.New("boom")))
+	restErr := utils.RestClientError{StatusCode: http.StatusNotFound, Err: errors.New("Not Found"),
+		Endpoint: "GET /api/v1/datastores"}
+	assert.Equal(t, "Not Found: GET /api/v1/datastores returned 404",
+		collectionError(ctx, fmt.Errorf("datastores: %w", restErr)))
+	assert.Equal(t, "Circuit breaker is open: GET /api/v1/datastores",
+		collectionError(ctx, utils.CircuitOpenError{Endpoint: "GET /api/v1/datastores"}))
+}
+
+func TestPerAssetCollectorStreams(t *testing.T) {
+	stream := func(_ commonclient.AssetInterface, _ context.Context, parentID, _ string,
+		handle commonclient.PageHandler[string]) error {
+		if parentID == "bad" {
+			return errors.New("down")
+		}
+		for page := 0; page < 2; page++ {
+			if err := handle([]string{fmt.Sprintf("%s-%d", parentID, page)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	req := FetchRequest{ParentIDs: []string{"a", "bad", "b"}, FanOut: NewFanOutExecutor(2, 0)}
+
+	var flat bytes.Buffer
+	req.Output = &flat
+	result := perAssetCollector("Flat", "F", "P", stream, false).Fetch(context.Background(), req)
+	assert.Equal(t, 4, result.Count)
+	assert.Equal(t, map[string]string{"bad": "down"}, result.Errors)
+	var items []string
+	assert.NoError(t, json.Unmarshal(flat.Bytes(), &items))
+	assert.ElementsMatch(t, []string{"a-0", "a-1", "b-0", "b-1"}, items)
+
+	var grouped bytes.Buffer
+	req.Output = &grouped
+	result = perAssetCollector("Grouped", "G", "P", stream, true).Fetch(context.Background(), req)
+	assert.Equal(t, 4, result.Count)
+	assert.JSONEq(t, `{"a":["a-0","a-1"],"b":["b-0","b-1"]}`, grouped.String())
+
+	var lines bytes.Buffer
+	req.Output, req.Format = &lines, handlers.NDJSONFormat
+	objects := func(_ commonclient.AssetInterface, _ context.Context, parentID, _ string,
+		handle commonclient.PageHandler[map[string]string]) error {
+		return handle([]map[string]string{{"id": parentID + "-0"}})
+	}
+	result = perAssetCollector("Lines", "L", "P", objects, true).Fetch(context.Background(), req)
+	assert.Equal(t, 3, result.Count)
+	assert.ElementsMatch(t, []string{`{"parentId":"a","id":"a-0"}`, `{"parentId":"bad","id":"bad-0"}`,
+		`{"parentId":"b","id":"b-0"}`}, strings.Split(strings.TrimSpace(lines.String()), "\n"))
+}
+
+func TestPerAssetCollectorPartialParents(t *testing.T) {
+	stream := func(_ commonclient.AssetInterface, _ context.Context, parentID, _ string,
+		handle commonclient.PageHandler[string]) error {
+		for page := 0; page < 3; page++ {
+			if parentID == "bad" && page == 1 {
+				return errors.New("page 2 failed")
+			}
+			if err := handle([]string{fmt.Sprintf("%s-%d", parentID, page)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	req := FetchRequest{ParentIDs: []string{"a", "bad"}, FanOut: NewFanOutExecutor(2, 0)}
+
+	var flat bytes.Buffer
+	req.Output = &flat
+	result := perAssetCollector("Partial", "P", "P", stream, false).Fetch(context.Background(), req)
+	assert.Equal(t, 4, result.Count, "pages are written as they are fetched")
+	assert.Equal(t, map[string]string{"bad": "page 2 failed (partial: 1 written before the failure)"},
+		result.Errors)
+	var items []string
+	assert.NoError(t, json.Unmarshal(flat.Bytes(), &items))
+	assert.ElementsMatch(t, []string{"a-0", "a-1", "a-2", "bad-0"}, items)
+
+	var grouped bytes.Buffer
+	req.Output = &grouped
+	result = perAssetCollector("Partial", "P", "P", stream, true).Fetch(context.Background(), req)
+	assert.Equal(t, 3, result.Count)
+	assert.Equal(t, map[string]string{"bad": "page 2 failed"}, result.Errors)
+	assert.JSONEq(t, `{"a":["a-0","a-1","a-2"]}`, grouped.String(), "a failed parent is left out of the object")
+}
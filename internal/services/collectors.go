@@ -0,0 +1,186 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils"
+)
+
+// Collector names, used as keys in the collection error map
+const (
+	VirtualMachines         = "VirtualMachines"
+	VMBackups               = "VMBackups"
+	VMSnapshots             = "VMSnapshots"
+	Datastores              = "Datastores"
+	DatastoreBackups        = "DatastoreBackups"
+	DatastoreSnapshots      = "DatastoreSnapshots"
+	DataOrchestrators       = "DataOrchestrators"
+	ProtectionPolicies      = "ProtectionPolicies"
+	VMProtectionGroups      = "VMProtectionGroup"
+	ProtectedVMs            = "ProtectedVMs"
+	CSPMachineInstances     = "CSPMachineInstances"
+	ZertoVPGs               = "ZertoVPGs"
+	ProtectionStores        = "ProtectionStores"
+	ProtectionStoreGateways = "ProtectionStoreGateways"
+	Storeonces              = "Storeonces"
+	CSPVolumes              = "CSPVolumes"
+	CSPAccounts             = "CSPAccounts"
+	MssqlDB                 = "MssqlDB"
+	MssqlBackups            = "MssqlBackups"
+	MssqlSnapshots          = "MssqlSnapshots"
+	MssqlInstances          = "MssqlInstances"
+	MssqlProtectionGroups   = "MssqlProtectionGroups"
+)
+
+// collectionError renders err for the collection error map. API failures and open circuits are
+// mapped to their REST status so the map tells which endpoint failed and why.
+func collectionError(ctx context.Context, err error) string {
+	if !errors.Is(err, utils.RestClientError{}) && !errors.Is(err, utils.CircuitOpenError{}) {
+		return err.Error()
+	}
+	_, msg := handlers.MapPFHErrorsToRESTErrorsWithContext(ctx, err)
+	return msg
+}
+
+// topLevelCollector builds a collector for an asset type listed with a single paginated call,
+// writing every page to the output as it is fetched. id is only required when other collectors
+// depend on this one.
+func topLevelCollector[T any](name, prefix string,
+	stream func(commonclient.AssetInterface, context.Context, string, commonclient.PageHandler[T]) error,
+	id func(T) string) Collector {
+	return Collector{
+		Name:   name,
+		Prefix: prefix,
+		Fetch: func(ctx context.Context, req FetchRequest) CollectorResult {
+			writer := WriterFor[T](req, false)
+			var ids []string
+			err := stream(req.Client, ctx, req.AuthHeader, func(items []T) error {
+				if id != nil {
+					for i := range items {
+						ids = append(ids, id(items[i]))
+					}
+				}
+				return WriteItems(writer, items)
+			})
+			if err == nil {
+				err = writer.Close()
+			}
+			if err != nil {
+				log.WithContext(ctx).Errorf("%s request failed : %v", name, err)
+				return CollectorResult{Errors: map[string]string{name: collectionError(ctx, err)}}
+			}
+			return CollectorResult{Count: writer.Count(), IDs: ids}
+		},
+	}
+}
+
+// perAssetCollector builds a collector issuing one paginated request chain per asset of its
+// parent, fanned out on the customer's executor. When groupByParent is set the JSON output is an
+// object keyed by the parent asset ID, otherwise a flat list. NDJSON and Parquet output have the
+// parent asset ID inlined in every line or row either way.
+//
+// The JSON object needs all the items of a parent together, so they are held in memory until its
+// last page and a parent failing partway is left out. Every other output is written page by page
+// to keep the memory bounded, a parent failing partway keeps the records written so far and its
+// error tells they are partial.
+func perAssetCollector[T any](name, prefix, parent string,
+	stream func(commonclient.AssetInterface, context.Context, string, string, commonclient.PageHandler[T]) error,
+	groupByParent bool) Collector {
+	return Collector{
+		Name:   name,
+		Prefix: prefix,
+		Parent: parent,
+		Fetch: func(ctx context.Context, req FetchRequest) CollectorResult {
+			writer := WriterFor[T](req, groupByParent)
+			var mu sync.Mutex
+			partial := make(map[string]int)
+			errs := FanOut(ctx, req.FanOut, req.ParentIDs, func(ctx context.Context, parentID string) error {
+				var err error
+				if writer.Keyed() {
+					var items []T
+					err = stream(req.Client, ctx, parentID, req.AuthHeader, func(page []T) error {
+						items = append(items, page...)
+						return nil
+					})
+					if err == nil && len(items) > 0 {
+						err = WriteGroup(writer, parentID, items)
+					}
+				} else {
+					written := 0
+					err = stream(req.Client, ctx, parentID, req.AuthHeader, func(page []T) error {
+						if err := WriteChildItems(writer, parentID, page); err != nil {
+							return err
+						}
+						written += len(page)
+						return nil
+					})
+					if err != nil && written > 0 {
+						mu.Lock()
+						partial[parentID] = written
+						mu.Unlock()
+					}
+				}
+				if err != nil {
+					log.WithContext(ctx).Errorf("%s request failed - %v : %v", name, parentID, err)
+				}
+				return err
+			})
+			for parentID, written := range partial {
+				errs[parentID] = fmt.Sprintf("%s (partial: %d written before the failure)", errs[parentID], written)
+			}
+			if err := writer.Close(); err != nil {
+				errs[name] = collectionError(ctx, err)
+			}
+			return CollectorResult{Count: writer.Count(), Errors: errs}
+		},
+	}
+}
+
+// DefaultCollectorRegistry returns the registry of every asset type collected by the common hauler.
+// Adding a new asset type only requires registering its collector here.
+func DefaultCollectorRegistry() *CollectorRegistry {
+	registry := NewCollectorRegistry()
+	registry.MustRegister(
+		topLevelCollector(VirtualMachines, "VM", commonclient.AssetInterface.StreamVMs,
+			func(vm model.VirtualMachine) string { return vm.ID }),
+		perAssetCollector(VMBackups, "VMBK", VirtualMachines, commonclient.AssetInterface.StreamVMBackups, false),
+		perAssetCollector(VMSnapshots, "VMSNP", VirtualMachines, commonclient.AssetInterface.StreamVMSnapshots, true),
+		topLevelCollector(Datastores, "DS", commonclient.AssetInterface.StreamDatastores,
+			func(ds model.Datastore) string { return ds.ID }),
+		perAssetCollector(DatastoreBackups, "DSBK", Datastores, commonclient.AssetInterface.StreamDSBackups, false),
+		perAssetCollector(DatastoreSnapshots, "DSSNP", Datastores, commonclient.AssetInterface.StreamDSSnapshots, true),
+		topLevelCollector[model.DO](DataOrchestrators, "DO", commonclient.AssetInterface.StreamDOs, nil),
+		topLevelCollector[model.ProtectionPolicy](ProtectionPolicies, "PP",
+			commonclient.AssetInterface.StreamProtectionPolicies, nil),
+		topLevelCollector[model.VMProtectionGroup](VMProtectionGroups, "VMPG",
+			commonclient.AssetInterface.StreamVMProtectionGroups, nil),
+		topLevelCollector[model.ProtectedVM](ProtectedVMs, "PVM", commonclient.AssetInterface.StreamProtectedVMs, nil),
+		topLevelCollector[model.CSPMachineInstance](CSPMachineInstances, "EC2",
+			commonclient.AssetInterface.StreamCSPMachineInstances, nil),
+		topLevelCollector[model.ZertoVPG](ZertoVPGs, "ZERTO", commonclient.AssetInterface.StreamZertoVPGs, nil),
+		topLevelCollector[model.ProtectionStore](ProtectionStores, "PS",
+			commonclient.AssetInterface.StreamProtectionStores, nil),
+		topLevelCollector[model.ProtectionStoreGateway](ProtectionStoreGateways, "PSG",
+			commonclient.AssetInterface.StreamProtectionStoreGateways, nil),
+		topLevelCollector[model.Storeonce](Storeonces, "STOREONCE", commonclient.AssetInterface.StreamStoreonces, nil),
+		topLevelCollector[model.CSPVolume](CSPVolumes, "EBS", commonclient.AssetInterface.StreamCSPVolumes, nil),
+		topLevelCollector[model.CSPAccount](CSPAccounts, "ACC", commonclient.AssetInterface.StreamCSPAccounts, nil),
+		topLevelCollector(MssqlDB, "MSSQL-DB", commonclient.AssetInterface.StreamMsSqlDB,
+			func(db model.MsSqlDB) string { return db.ID }),
+		perAssetCollector(MssqlBackups, "MSSQL-BK", MssqlDB, commonclient.AssetInterface.StreamDBBackups, false),
+		perAssetCollector(MssqlSnapshots, "MSSQL-SNP", MssqlDB, commonclient.AssetInterface.StreamDBSnapshots, false),
+		topLevelCollector[model.MsSqlInstance](MssqlInstances, "MSSQL-DBINS",
+			commonclient.AssetInterface.StreamMsSqlInstances, nil),
+		topLevelCollector[model.MsSqlProtectionGroup](MssqlProtectionGroups, "MSSQL-DBPG",
+			commonclient.AssetInterface.StreamMsSqlProtectionGroups, nil),
+	)
+	return registry
+}
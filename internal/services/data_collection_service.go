@@ -4,14 +4,17 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/xid"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/sink"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
@@ -49,581 +52,294 @@ const (
 	DeviceType4           = "deviceType4"
 	Common                = "Common"
 	jsonExt               = ".json"
+	ndjsonExt             = ".ndjson"
+	parquetExt            = ".parquet"
 	PARTITIONKEY          = "partitionKey"
 	equalExt              = "="
 )
 
 type dataCollectionService struct {
 	commonClient commonclient.AssetInterface
+	sink         sink.Sink
+	registry     *CollectorRegistry
+	concurrency  int
+	fanOut       *FanOutExecutor
+	harmonyRetry HarmonyRetryPolicy
 	ctx          context.Context
 }
 
-func NewDataCollectionService(ctx context.Context,
-	aClient commonclient.AssetInterface) DataCollectionServiceInterface {
+func NewDataCollectionService(ctx context.Context, aClient commonclient.AssetInterface,
+	fileSink sink.Sink) DataCollectionServiceInterface {
 	return &dataCollectionService{
 		commonClient: aClient,
+		sink:         fileSink,
+		registry:     DefaultCollectorRegistry(),
+		concurrency:  configs.GetCollectorConcurrency(),
+		fanOut:       FanOutExecutorFromConfig(),
+		harmonyRetry: HarmonyRetryPolicyFromConfig(),
 		ctx:          ctx,
 	}
 }
 
 type DataCollectionServiceInterface interface {
 	CollectDeviceInformation(ctx context.Context, consumerDetails handlers.ConsumerDetails,
-		schedulerProducer producer.Producer, harmonyProducer producer.Producer)
+		schedulerProducer producer.Producer, harmonyProducer producer.Producer) CollectionResult
 }
 
-var UploadToS3 = func(ctx context.Context, jsonContent *[]byte, bucketName, awsS3Region, awsAccessKeyID,
-	awsSecretAccessKey string, uploadType UploadType) (string, int, error) {
-	log.Debugf("bucketName: %v awsS3Region %v awsAccessKeyID %v awsSecretAccessKey %v uploadType %v\n",
-		bucketName, awsS3Region, awsAccessKeyID, awsSecretAccessKey, uploadType)
-	keyname, filesize, err := UploadToAwsS3(ctx, jsonContent, bucketName, awsS3Region, awsAccessKeyID,
-		awsSecretAccessKey, uploadType)
-	return keyname, filesize, err
+// CollectionResult is the outcome of a collection, as announced by its end of collection event.
+type CollectionResult struct {
+	Status       string    `json:"status"`
+	UploadStatus string    `json:"uploadStatus"`
+	ManifestKey  string    `json:"manifestKey"`
+	ManifestSize int64     `json:"manifestSize"`
+	Errors       string    `json:"errors,omitempty"`
+	EndTime      time.Time `json:"endTime"`
 }
 
-func ConstructS3Object() string {
+func newCollectionResult(collectionStatus string, manifest sink.Object, manifestErr error,
+	errorMap map[string]map[string]string) CollectionResult {
+	uploadStatus := Success
+	if manifestErr != nil {
+		uploadStatus = Failed
+	}
+	return CollectionResult{
+		Status:       collectionStatus,
+		UploadStatus: uploadStatus,
+		ManifestKey:  manifest.Key,
+		ManifestSize: manifest.Size,
+		Errors:       encodeErrors(errorMap),
+		EndTime:      time.Now().UTC(),
+	}
+}
+
+// ConstructS3Object returns the partitioned name of the files of a collection written in format.
+func ConstructS3Object(format string) string {
 	now := time.Now().UTC()
 	nows := fmt.Sprintf("%v", now)
 	word1 := strings.Split(string(nows), " ")
 	word2 := strings.Split(word1[1], ":")
 	partitionKey := fmt.Sprintf("%v", word1[0]+"-"+word2[0]+"-"+word2[1])
 	collectionID := xid.New().String()
-	return PARTITIONKEY + equalExt + partitionKey + "/" + collectionID + jsonExt
+	return PARTITIONKEY + equalExt + partitionKey + "/" + collectionID + fileExtension(format)
+}
+
+func fileExtension(format string) string {
+	switch format {
+	case handlers.NDJSONFormat:
+		return ndjsonExt
+	case handlers.ParquetFormat:
+		return parquetExt
+	default:
+		return jsonExt
+	}
+}
+
+func contentType(format string) string {
+	switch format {
+	case handlers.NDJSONFormat:
+		return sink.NDJSONContentType
+	case handlers.ParquetFormat:
+		return sink.ParquetContentType
+	default:
+		return sink.JSONContentType
+	}
 }
 
-//nolint:funlen,gocyclo,ineffassign // Code can be lengthy without a need for decomposition
 func (dc *dataCollectionService) CollectDeviceInformation(ctx context.Context, consumerDetails handlers.ConsumerDetails,
-	schedulerProducer producer.Producer, harmonyProducer producer.Producer) {
-	//var collectionStartTime = time.Now().UTC().String()
+	schedulerProducer producer.Producer, harmonyProducer producer.Producer) CollectionResult {
 	var mainErrorMap = make(map[string]map[string]string)
-	var mainVMMap = make([]interface{}, 0)
-	var mainDSMap = make([]interface{}, 0)
-	var mainPPMap = make([]interface{}, 0)
-	var mainVPGMap = make([]interface{}, 0)
-	var mainPVMMap = make([]interface{}, 0)
-	var mainCSPMIMap = make([]interface{}, 0)
-	var mainPSMap = make([]interface{}, 0)
-	var mainPSGMap = make([]interface{}, 0)
-	var mainDataOrchestratorMap = make([]interface{}, 0)
-	var mainSOMap = make([]interface{}, 0)
-	var mainCSPVMap = make([]interface{}, 0)
-	var mainCSPAMap = make([]interface{}, 0)
-	var mainZertoVPGMap = make([]interface{}, 0)
-	var mainVMBackupMap = make([]interface{}, 0)
-	var mainVMSnapshotMap = make(map[string][]interface{})
-	var mainDSBackupMap = make([]interface{}, 0)
-	var mainDSSnapshotMap = make(map[string][]interface{})
-
-	var mainDBMap = make([]interface{}, 0)
-	var mainDBInsMap = make([]interface{}, 0)
-	var mainDBPGMap = make([]interface{}, 0)
-	var mainDBBackupMap = make([]interface{}, 0)
-	var mainDBSnapshotMap = make([]interface{}, 0)
 
 	dc.commonClient.SetCustomerIDForRest(consumerDetails.ApplicationCustomerID)
 
+	start := time.Now().UTC()
+	pKey := ConstructS3Object(consumerDetails.OutputFormat)
+	var reports []CollectorReport
 	authHeader, authErr := dc.commonClient.GetAuthHeaderForRest()
 	if authErr != nil {
 		log.WithContext(ctx).Errorf("Auth request failed : %v", authErr)
-		return
-	}
-
-	pKey := ConstructS3Object()
-	virtualmachines, vErr := dc.commonClient.GetVMs(ctx, authHeader)
-	if vErr != nil {
-		log.WithContext(ctx).Errorf("GetVMs request failed : %v", vErr)
-		handlers.SetNested(mainErrorMap, "VirtualMachines", "VirtualMachines", vErr.Error())
-	}
-
-	if virtualmachines != nil {
-		log.WithContext(ctx).Infof("VM count - %v", len(virtualmachines))
-		for v := range virtualmachines {
-			mainVMMap = append(mainVMMap, virtualmachines[v])
-		}
-		u, err := json.Marshal(mainVMMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./vmsTest", u, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &u, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/VM/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-	}
-
-	for v := range virtualmachines {
-		vmbackups, vmbkpErr := dc.commonClient.GetVMBackups(ctx, virtualmachines[v].ID, authHeader)
-		if vmbkpErr != nil {
-			log.WithContext(ctx).Errorf("GetVMBackups request failed - %v : %v",
-				virtualmachines[v].ID, vmbkpErr.Error())
-			handlers.SetNested(mainErrorMap, "VMBackups", virtualmachines[v].ID, vmbkpErr.Error())
-		}
-		for vb := range vmbackups {
-			mainVMBackupMap = append(mainVMBackupMap, vmbackups[vb])
-		}
-	}
-	bkup, err := json.Marshal(mainVMBackupMap)
-	if err != nil {
-		log.WithContext(ctx).Errorf("err is %v", err)
-	}
-	err = os.WriteFile("./vmBackups", bkup, 0644)
-	log.WithContext(ctx).Errorf("file write err is %v", err)
-
-	UploadToS3(ctx, &bkup, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/VMBK/"+pKey))
-
-	for v := range virtualmachines {
-		vmSnapshots, vmsnapErr := dc.commonClient.GetVMSnapshots(ctx, virtualmachines[v].ID, authHeader)
-		if vmsnapErr != nil {
-			log.WithContext(ctx).Errorf("GetVMSnapshots request failed - %v : %v",
-				virtualmachines[v].ID, vmsnapErr.Error())
-			handlers.SetNested(mainErrorMap, "VMSnapshots", virtualmachines[v].ID, vmsnapErr.Error())
-		}
-		for vs := range vmSnapshots {
-			mainVMSnapshotMap[virtualmachines[v].ID] = append(mainVMSnapshotMap[virtualmachines[v].ID], vmSnapshots[vs])
-		}
-	}
-	snap, err := json.Marshal(mainVMSnapshotMap)
-	if err != nil {
-		log.WithContext(ctx).Errorf("err is %v", err)
-	}
-	err = os.WriteFile("./vmSnapshots", snap, 0644)
-	log.WithContext(ctx).Errorf("file write err is %v", err)
-	key, filesize, err := UploadToS3(ctx, &snap, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/VMSNP/"+pKey))
-	log.WithContext(ctx).Infof("Err = %v, ke:y= %v, filesize = %v", err, key, filesize)
-	virtualmachines = nil
-
-	datastores, dErr := dc.commonClient.GetDatastores(ctx, authHeader)
-	if dErr != nil {
-		log.WithContext(ctx).Errorf("GetDatastores request failed : %v", dErr)
-		handlers.SetNested(mainErrorMap, "Datastores", "Datastores", dErr.Error())
-	}
-
-	if datastores != nil {
-		log.WithContext(ctx).Infof("Datastores count - %v", len(datastores))
-		for d := range datastores {
-			mainDSMap = append(mainDSMap, datastores[d])
-		}
-		u, err := json.Marshal(mainDSMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./datastoreTest", u, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &u, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/DS/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-	}
-
-	for d := range datastores {
-		dsbackups, dsbkpErr := dc.commonClient.GetDSBackups(ctx, datastores[d].ID, authHeader)
-		if dsbkpErr != nil {
-			log.WithContext(ctx).Errorf("GetDSBackups request failed - %v : %v",
-				datastores[d].ID, dsbkpErr.Error())
-			handlers.SetNested(mainErrorMap, "DatastoreBackups", datastores[d].ID, dsbkpErr.Error())
-		}
-		for db := range dsbackups {
-			//mainDSBackupMap[datastores[d].ID] = append(mainDSBackupMap[datastores[d].ID], dsbackups[db])
-			mainDSBackupMap = append(mainDSBackupMap, dsbackups[db])
-		}
-	}
-	u, err := json.Marshal(mainDSBackupMap)
-	if err != nil {
-		log.WithContext(ctx).Errorf("err is %v", err)
-	}
-	err = os.WriteFile("./datastoreBKTest", u, 0644)
-	log.WithContext(ctx).Errorf("file write err is %v", err)
-
-	key, filesize, err = UploadToS3(ctx, &u, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/DSBK/"+pKey))
-	log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-
-	for d := range datastores {
-		dsSnapshots, dssnapErr := dc.commonClient.GetDSSnapshots(ctx, datastores[d].ID, authHeader)
-		if dssnapErr != nil {
-			log.WithContext(ctx).Errorf("GetDSSnapshots request failed - %v : %v",
-				datastores[d].ID, dssnapErr.Error())
-			handlers.SetNested(mainErrorMap, "DatastoreSnapshots", datastores[d].ID, dssnapErr.Error())
-		}
-		for ds := range dsSnapshots {
-			mainDSSnapshotMap[datastores[d].ID] = append(mainDSSnapshotMap[datastores[d].ID], dsSnapshots[ds])
-		}
-	}
-	dssnap, err := json.Marshal(mainDSSnapshotMap)
-	if err != nil {
-		log.WithContext(ctx).Errorf("err is %v", err)
-	}
-	err = os.WriteFile("./datastoreSnapshotTest", dssnap, 0644)
-	log.WithContext(ctx).Errorf("file write err is %v", err)
-	key, filesize, err = UploadToS3(ctx, &dssnap, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/DSSNP/"+pKey))
-	log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-
-	datastores = nil
-
-	dataOrchestrators, dErr := dc.commonClient.GetDOs(ctx, authHeader)
-	if dErr != nil {
-		log.WithContext(ctx).Errorf("GetDos request failed : %v", dErr)
-		handlers.SetNested(mainErrorMap, "DataOrchestrators", "DataOrchestrators", dErr.Error())
-	}
-
-	if dataOrchestrators != nil {
-		log.WithContext(ctx).Infof("DataOrchestrators count - %v", len(dataOrchestrators))
-		for p := range dataOrchestrators {
-			mainDataOrchestratorMap = append(mainDataOrchestratorMap, dataOrchestrators[p])
-		}
-		pp, err := json.Marshal(mainDataOrchestratorMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./doTest", pp, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &pp, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/DO/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		dataOrchestrators = nil
-	}
-
-	protectionpolicies, pErr := dc.commonClient.GetProtectionPolicies(ctx, authHeader)
-	if pErr != nil {
-		log.WithContext(ctx).Errorf("GetProtectionPolicies request failed : %v", pErr)
-		handlers.SetNested(mainErrorMap, "ProtectionPolicies", "ProtectionPolicies", pErr.Error())
-	}
-
-	if protectionpolicies != nil {
-		log.WithContext(ctx).Infof("ProtectionPolicies count - %v", len(protectionpolicies))
-		for p := range protectionpolicies {
-			mainPPMap = append(mainPPMap, protectionpolicies[p])
-		}
-		pp, err := json.Marshal(mainPPMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./ppTest", pp, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &pp, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/PP/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-
-		protectionpolicies = nil
-	}
-
-	vpg, vpgErr := dc.commonClient.GetVMProtectionGroups(ctx, authHeader)
-	if vpgErr != nil {
-		log.WithContext(ctx).Errorf("GetVMProtectionGroups request failed : %v", vpgErr)
-		handlers.SetNested(mainErrorMap, "VMProtectionGroup", "VMProtectionGroup", vpgErr.Error())
-	}
-
-	if vpg != nil {
-		log.WithContext(ctx).Infof("GetVMProtectionGroups count - %v", len(vpg))
-		for v := range vpg {
-			mainVPGMap = append(mainVPGMap, vpg[v])
-		}
-		vpg, err := json.Marshal(mainVPGMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./vpgTest", vpg, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &vpg, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/VMPG/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-
-		vpg = nil
-	}
-
-	pvms, pvmErr := dc.commonClient.GetProtectedVMs(ctx, authHeader)
-	if pvmErr != nil {
-		log.WithContext(ctx).Errorf("GetProtectedVMs request failed : %v", pvmErr)
-		handlers.SetNested(mainErrorMap, "ProtectedVMs", "ProtectedVMs", pvmErr.Error())
-	}
-
-	if pvms != nil {
-		log.WithContext(ctx).Infof("GetProtectedVMs count - %v", len(pvms))
-		for p := range pvms {
-			mainPVMMap = append(mainPVMMap, pvms[p])
-		}
-		pvm, err := json.Marshal(mainPVMMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./pvmTest", pvm, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &pvm, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/PVM/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-
-		pvms = nil
-	}
-
-	cspMI, cspErr := dc.commonClient.GetCSPMachineInstances(ctx, authHeader)
-	if cspErr != nil {
-		log.WithContext(ctx).Errorf("GetCSPMachineInstances request failed : %v", cspErr)
-		handlers.SetNested(mainErrorMap, "CSPMachineInstances", "CSPMachineInstances", cspErr.Error())
-	}
-
-	if cspMI != nil {
-		log.WithContext(ctx).Infof("GetCSPMachineInstances count - %v", len(cspMI))
-		for csp := range cspMI {
-			mainCSPMIMap = append(mainCSPMIMap, cspMI[csp])
-		}
-		csp, err := json.Marshal(mainCSPMIMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./pvmTest", csp, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &csp, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/EC2/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		cspMI = nil
-	}
-
-	zvpgs, zvpgErr := dc.commonClient.GetZertoVPGs(ctx, authHeader)
-	if zvpgErr != nil {
-		log.WithContext(ctx).Errorf("GetZertoVPGs request failed : %v", zvpgErr)
-		handlers.SetNested(mainErrorMap, "ZertoVPGs", "ZertoVPGs", zvpgErr.Error())
-	}
-
-	if zvpgs != nil {
-		log.WithContext(ctx).Infof("GetZertoVPGs count - %v", len(zvpgs))
-		for zvpg := range zvpgs {
-			mainZertoVPGMap = append(mainZertoVPGMap, zvpgs[zvpg])
-		}
-		zmap, err := json.Marshal(mainZertoVPGMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./pvmTest", zmap, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &zmap, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/ZERTO/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		zvpgs = nil
-	}
-
-	ps, psErr := dc.commonClient.GetProtectionStores(ctx, authHeader)
-	if psErr != nil {
-		log.WithContext(ctx).Errorf("GetProtectionStores request failed : %v", psErr)
-		handlers.SetNested(mainErrorMap, "ProtectionStores", "ProtectionStores", psErr.Error())
-	}
-
-	if ps != nil {
-		log.WithContext(ctx).Infof("GetProtectionStores count - %v", len(ps))
-		for pst := range ps {
-			mainPSMap = append(mainPSMap, ps[pst])
-		}
-		u, err := json.Marshal(mainPSMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./psTest", u, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &u, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/PS/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		ps = nil
-	}
-
-	psg, psgErr := dc.commonClient.GetProtectionStoreGateways(ctx, authHeader)
-	if psgErr != nil {
-		log.WithContext(ctx).Errorf("GetProtectionStoreGateways request failed : %v", psgErr)
-		handlers.SetNested(mainErrorMap, "ProtectionStoreGateways", "ProtectionStoreGateways", psgErr.Error())
-	}
+		handlers.SetNested(mainErrorMap, AuthError, AuthError, collectionError(ctx, authErr))
+	} else {
+		reports = dc.runCollectors(ctx, authHeader, pKey, consumerDetails.OutputFormat, dc.fanOut, mainErrorMap)
+		log.WithContext(ctx).Infof("Collection %v finished with %v asset types and %v errors",
+			consumerDetails.CollectionID, len(reports), len(mainErrorMap))
+	}
+
+	dc.notifyUploads(ctx, consumerDetails, reports, mainErrorMap, harmonyProducer)
+	publishStatuses(ctx, consumerDetails, reports, schedulerProducer)
+
+	// the manifest is written last so consumers seeing it know every file of the collection is in place
+	manifest := NewManifest(consumerDetails, start, time.Now().UTC(), reports, mainErrorMap)
+	object, err := dc.publishManifest(ctx, consumerDetails, manifest, manifestKey(pKey, consumerDetails.OutputFormat),
+		harmonyProducer)
+	result := newCollectionResult(manifest.Status, object, err, mainErrorMap)
+	PublishEndOfCollection(ctx, consumerDetails, result, schedulerProducer)
+	return result
+}
 
-	if psg != nil {
-		log.WithContext(ctx).Infof("GetProtectionStoreGateways count - %v", len(psg))
-		for psgy := range psg {
-			mainPSGMap = append(mainPSGMap, psg[psgy])
+// publishStatuses sends the upload and collection status of every asset type to the scheduler topic.
+func publishStatuses(ctx context.Context, consumerDetails handlers.ConsumerDetails, reports []CollectorReport,
+	schedulerProducer producer.Producer) {
+	for _, report := range reports {
+		uploadStatus := Success
+		if report.Key == "" {
+			uploadStatus = Failed
 		}
-		m, err := json.Marshal(mainPSGMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
+		errs := report.Errors
+		if report.Err != nil {
+			errs = map[string]string{report.Name: report.Err.Error()}
 		}
-		err = os.WriteFile("./psmTest", m, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &m, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/PSG/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		psg = nil
-	}
-
-	sos, sosErr := dc.commonClient.GetStoreonces(ctx, authHeader)
-	if sosErr != nil {
-		log.WithContext(ctx).Errorf("GetStoreonces request failed : %v", sosErr)
-		handlers.SetNested(mainErrorMap, "Storeonces", "Storeonces", sosErr.Error())
+		handlers.PublishtoSchedulerKafka(ctx, consumerDetails, Common, uploadStatus, report.Status(), report.Prefix,
+			strconv.Itoa(report.FileSize), report.Key, encodeErrors(errs), schedulerProducer)
 	}
+}
 
-	if sos != nil {
-		log.WithContext(ctx).Infof("GetStoreonces count - %v", len(sos))
-		for so := range sos {
-			mainSOMap = append(mainSOMap, sos[so])
-		}
-		sMap, err := json.Marshal(mainSOMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./sosTest", sMap, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &sMap, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/STOREONCE/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		sos = nil
-	}
+// PublishEndOfCollection sends the end of collection event to the scheduler topic, with the aggregated
+// status of the collection and its manifest as file.
+func PublishEndOfCollection(ctx context.Context, consumerDetails handlers.ConsumerDetails, result CollectionResult,
+	schedulerProducer producer.Producer) {
+	consumerDetails.CollectionType = handlers.EndOfCollection
+	handlers.PublishtoSchedulerKafka(ctx, consumerDetails, Common, result.UploadStatus, result.Status,
+		handlers.UnknownDeviceType, strconv.FormatInt(result.ManifestSize, 10), result.ManifestKey, result.Errors,
+		schedulerProducer)
+}
 
-	cspv, cspvErr := dc.commonClient.GetCSPVolumes(ctx, authHeader)
-	if cspvErr != nil {
-		log.WithContext(ctx).Errorf("GetCSPVolumes request failed : %v", cspvErr)
-		handlers.SetNested(mainErrorMap, "CSPVolumes", "CSPVolumes", cspvErr.Error())
-	}
+// PublishFailedCollection reports a collection that failed before any asset was collected, sending a
+// failed status and the end of collection event with errorMap as errors.
+func PublishFailedCollection(ctx context.Context, consumerDetails handlers.ConsumerDetails,
+	errorMap map[string]map[string]string, schedulerProducer producer.Producer) CollectionResult {
+	result := CollectionResult{
+		Status:       Failed,
+		UploadStatus: Failed,
+		Errors:       encodeErrors(errorMap),
+		EndTime:      time.Now().UTC(),
+	}
+	handlers.PublishtoSchedulerKafka(ctx, consumerDetails, Common, result.UploadStatus, result.Status,
+		handlers.UnknownDeviceType, "0", "", result.Errors, schedulerProducer)
+	PublishEndOfCollection(ctx, consumerDetails, result, schedulerProducer)
+	return result
+}
 
-	if cspv != nil {
-		log.WithContext(ctx).Infof("GetCSPVolumes count - %v", len(cspv))
-		for so := range cspv {
-			mainCSPVMap = append(mainCSPVMap, cspv[so])
-		}
-		sMap, err := json.Marshal(mainCSPVMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./cspTest", sMap, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &sMap, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/EBS/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		cspv = nil
+// encodeErrors renders an error map for the scheduler events, empty when there is no error.
+func encodeErrors[T any](errs map[string]T) string {
+	if len(errs) == 0 {
+		return ""
 	}
+	bs, _ := json.Marshal(errs)
+	return string(bs)
+}
 
-	cspa, cspaErr := dc.commonClient.GetCSPAccounts(ctx, authHeader)
-	if cspaErr != nil {
-		log.WithContext(ctx).Errorf("GetCSPAccounts request failed : %v", cspaErr)
-		handlers.SetNested(mainErrorMap, "CSPAccounts", "CSPAccounts", cspaErr.Error())
-	}
+// runCollectors runs the registered collectors on a pool of at most dc.concurrency workers.
+// Independent collectors run in parallel while a dependent collector only starts once its
+// parent has fetched, receiving the parent asset IDs. Reports are returned in registration order.
+func (dc *dataCollectionService) runCollectors(ctx context.Context, authHeader, pKey, format string,
+	fanOut *FanOutExecutor, errorMap map[string]map[string]string) []CollectorReport {
+	collectors := dc.registry.Collectors()
+	reports := make([]CollectorReport, len(collectors))
+	fetched := make(map[string]chan struct{}, len(collectors))
+	for _, collector := range collectors {
+		fetched[collector.Name] = make(chan struct{})
+	}
+	workers := dc.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	slots := make(chan struct{}, workers)
+	parentIDs := make(map[string][]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := range collectors {
+		wg.Add(1)
+		go func(i int, collector Collector) {
+			defer wg.Done()
+			var ids []string
+			if collector.Parent != "" {
+				<-fetched[collector.Parent]
+				mu.Lock()
+				ids = parentIDs[collector.Parent]
+				mu.Unlock()
+			}
+
+			var result CollectorResult
+			upload := newStreamUpload(ctx, dc.sink, configs.GetSourceType()+"/"+collector.Prefix+"/"+pKey,
+				contentType(format))
+			if acquireSlot(ctx, slots) {
+				defer func() { <-slots }()
+				result = collector.Fetch(ctx, FetchRequest{
+					Client:     dc.commonClient,
+					AuthHeader: authHeader,
+					ParentIDs:  ids,
+					FanOut:     fanOut,
+					Output:     upload,
+					Format:     format,
+				})
+			} else {
+				result = CollectorResult{Errors: map[string]string{collector.Name: ctx.Err().Error()}}
+			}
+			mu.Lock()
+			if result.IDs != nil {
+				parentIDs[collector.Name] = result.IDs
+			}
+			mu.Unlock()
+			close(fetched[collector.Name])
+
+			reports[i] = dc.recordResult(ctx, collector, result, upload, errorMap, &mu)
+		}(i, collectors[i])
+	}
+	wg.Wait()
+	return reports
+}
 
-	if cspa != nil {
-		log.WithContext(ctx).Infof("GetCSPAccounts count - %v", len(cspa))
-		for so := range cspa {
-			mainCSPAMap = append(mainCSPAMap, cspa[so])
-		}
-		cspMap, err := json.Marshal(mainCSPAMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./cspTest", cspMap, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &cspMap, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/ACC/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		cspa = nil
-	}
-	
-	dbs, dbErr := dc.commonClient.GetMsSqlDB(ctx, authHeader)
-	if dbErr != nil {
-		log.WithContext(ctx).Errorf("GetDbs request failed : %v", dbErr)
-		handlers.SetNested(mainErrorMap, "MssqlDB", "MssqlDB", dbErr.Error())
+// acquireSlot blocks until a worker slot is free, returning false once ctx is done.
+func acquireSlot(ctx context.Context, slots chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
 	}
-
-	if dbs != nil {
-		log.WithContext(ctx).Infof("Databases count - %v", len(dbs))
-		for p := range dbs {
-			mainDBMap = append(mainDBMap, dbs[p])
-		}
-		pp, err := json.Marshal(mainDBMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./dbTest", pp, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &pp, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/MSSQL-DB/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
 	}
+}
 
-	for v := range dbs {
-		dbbackups, dbbkpErr := dc.commonClient.GetDBBackups(ctx, dbs[v].ID, authHeader)
-		if dbbkpErr != nil {
-			log.WithContext(ctx).Errorf("GetDBBackups request failed - %v : %v",
-				dbs[v].ID, dbbkpErr.Error())
-			handlers.SetNested(mainErrorMap, "MssqlBackups", dbs[v].ID, dbbkpErr.Error())
-		}
-		for i := range dbbackups {
-			mainDBBackupMap = append(mainDBBackupMap, dbbackups[i])
-		}
+// recordResult completes the upload of a collector and merges its errors into the shared error map.
+func (dc *dataCollectionService) recordResult(ctx context.Context, collector Collector, result CollectorResult,
+	upload *streamUpload, errorMap map[string]map[string]string, mu *sync.Mutex) CollectorReport {
+	report := finishUpload(ctx, collector, result, upload)
+	mu.Lock()
+	defer mu.Unlock()
+	for k, v := range report.Errors {
+		handlers.SetNested(errorMap, collector.Name, k, v)
 	}
-	dbbkup, err := json.Marshal(mainDBBackupMap)
-	if err != nil {
-		log.WithContext(ctx).Errorf("err is %v", err)
-	}
-	err = os.WriteFile("./dbBackups", dbbkup, 0644)
-	log.WithContext(ctx).Errorf("file write err is %v", err)
+	return report
+}
 
-	key, filesize, err = UploadToS3(ctx, &dbbkup, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/MSSQL-BK/"+pKey))
-	log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-	for v := range dbs {
-		dbSnapshots, dbsnapErr := dc.commonClient.GetDBSnapshots(ctx, dbs[v].ID, authHeader)
-		if dbsnapErr != nil {
-			log.WithContext(ctx).Errorf("GetDBSnapshots request failed - %v : %v",
-				dbs[v].ID, dbsnapErr.Error())
-			handlers.SetNested(mainErrorMap, "MssqlSnapshots", dbs[v].ID, dbsnapErr.Error())
-		}
-		for i := range dbSnapshots {
-			mainDBSnapshotMap = append(mainDBSnapshotMap, dbSnapshots[i])
-		}
+// finishUpload completes the upload streamed by a collector, or aborts it when the whole asset
+// type failed so no truncated document is left in the sink.
+func finishUpload(ctx context.Context, collector Collector, result CollectorResult,
+	upload *streamUpload) CollectorReport {
+	report := CollectorReport{
+		Name:   collector.Name,
+		Prefix: collector.Prefix,
+		Count:  result.Count,
+		Errors: result.Errors,
+	}
+	var abort error
+	if msg, failed := result.Errors[collector.Name]; failed {
+		abort = errors.New(msg)
+	}
+	started, object, err := upload.finish(abort)
+	if !started || abort != nil {
+		log.WithContext(ctx).Infof("%s skipped upload, errors = %v", collector.Name, len(result.Errors))
+		return report
 	}
-	dbsnap, err := json.Marshal(mainDBSnapshotMap)
 	if err != nil {
-		log.WithContext(ctx).Errorf("err is %v", err)
+		report.Err = err
+	} else {
+		report.Key, report.FileSize, report.SHA256 = object.Key, int(object.Size), object.SHA256
 	}
-	err = os.WriteFile("./dbSnapshots", dbsnap, 0644)
-	log.WithContext(ctx).Errorf("file write err is %v", err)
-	key, filesize, err = UploadToS3(ctx, &dbsnap, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-		configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/MSSQL-SNP/"+pKey))
-	dbs = nil
-
-	dbIns, dbErr := dc.commonClient.GetMsSqlInstances(ctx, authHeader)
-	if dbErr != nil {
-		log.WithContext(ctx).Errorf("GetDos request failed : %v", dbErr)
-		handlers.SetNested(mainErrorMap, "MssqlInstances", "MssqlInstances", dbErr.Error())
-	}
-
-	if dbIns != nil {
-		log.WithContext(ctx).Infof("Databases count - %v", len(dbIns))
-		for p := range dbIns {
-			mainDBInsMap = append(mainDBInsMap, dbIns[p])
-		}
-		pp, err := json.Marshal(mainDBInsMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./dbInsTest", pp, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &pp, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/MSSQL-DBINS/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		dbIns = nil
-	}
-	dbPG, dbErr := dc.commonClient.GetMsSqlProtectionGroups(ctx, authHeader)
-	if dbErr != nil {
-		log.WithContext(ctx).Errorf("GetDB PGs request failed : %v", dbErr)
-		handlers.SetNested(mainErrorMap, "MssqlProtectionGroups", "MssqlProtectionGroups", dbErr.Error())
-	}
-
-	if dbPG != nil {
-		log.WithContext(ctx).Infof("Databases count - %v", len(dbPG))
-		for p := range dbPG {
-			mainDBPGMap = append(mainDBPGMap, dbPG[p])
-		}
-		pp, err := json.Marshal(mainDBPGMap)
-		if err != nil {
-			log.WithContext(ctx).Errorf("err is %v", err)
-		}
-		err = os.WriteFile("./dbPGTest", pp, 0644)
-		log.WithContext(ctx).Errorf("file write err is %v", err)
-		key, filesize, err := UploadToS3(ctx, &pp, configs.GetAWSS3BucketName(), configs.GetAWSRegion(),
-			configs.GetAWSAccessKey(), configs.GetAWSSecretAccessKey(), UploadType(configs.GetSourceType()+"/MSSQL-DBPG/"+pKey))
-		log.WithContext(ctx).Infof("Err = %v, key= %v, filesize = %v", err, key, filesize)
-		dbPG = nil
-	}
-
+	log.WithContext(ctx).Infof("%s count - %v, err = %v, key = %v, filesize = %v", collector.Name,
+		report.Count, report.Err, report.Key, report.FileSize)
+	return report
 }
@@ -0,0 +1,189 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/sink"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+type fakeSink struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{files: make(map[string][]byte)}
+}
+
+func (s *fakeSink) Put(_ context.Context, key string, content io.Reader, _ sink.Attributes) (sink.Object, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return sink.Object{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[key] = data
+	return sink.Object{Key: key, Size: int64(len(data))}, nil
+}
+
+func (s *fakeSink) Location() string {
+	return "fake"
+}
+
+func TestRunCollectorsOrderingAndConcurrency(t *testing.T) {
+	var active, maxActive int32
+	track := func() func() {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return func() { atomic.AddInt32(&active, -1) }
+	}
+	topLevel := func(ids ...string) FetchFunc {
+		return func(_ context.Context, req FetchRequest) CollectorResult {
+			defer track()()
+			writer := NewJSONArrayWriter(req.Output)
+			if err := WriteItems(writer, ids); err != nil {
+				return CollectorResult{Errors: map[string]string{"write": err.Error()}}
+			}
+			_ = writer.Close()
+			return CollectorResult{Count: writer.Count(), IDs: ids}
+		}
+	}
+
+	var childParentIDs []string
+	registry := NewCollectorRegistry()
+	registry.MustRegister(
+		Collector{Name: "Parent", Prefix: "P", Fetch: topLevel("p1", "p2")},
+		Collector{Name: "Child", Prefix: "C", Parent: "Parent",
+			Fetch: func(_ context.Context, req FetchRequest) CollectorResult {
+				defer track()()
+				childParentIDs = req.ParentIDs
+				_ = NewJSONArrayWriter(req.Output).Close()
+				return CollectorResult{Errors: map[string]string{"p2": "boom"}}
+			}},
+		Collector{Name: "Other1", Prefix: "O1", Fetch: topLevel()},
+		Collector{Name: "Other2", Prefix: "O2", Fetch: topLevel()},
+		Collector{Name: "Failed", Prefix: "F",
+			Fetch: func(_ context.Context, req FetchRequest) CollectorResult {
+				_ = WriteItems(NewJSONArrayWriter(req.Output), []string{"partial"})
+				return CollectorResult{Errors: map[string]string{"Failed": errors.New("down").Error()}}
+			}},
+	)
+	files := newFakeSink()
+	dc := &dataCollectionService{registry: registry, sink: files, concurrency: 2}
+	errorMap := make(map[string]map[string]string)
+	reports := dc.runCollectors(context.Background(), "token", "pkey", handlers.JSONFormat,
+		NewFanOutExecutor(2, 0), errorMap)
+
+	assert.Equal(t, []string{"p1", "p2"}, childParentIDs)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxActive), int32(2))
+	assert.Len(t, reports, 5)
+	assert.Equal(t, "Parent", reports[0].Name)
+	assert.Equal(t, 2, reports[0].Count)
+	assert.Contains(t, reports[0].Key, "/P/pkey")
+	assert.Equal(t, "", reports[4].Key)
+	assert.Equal(t, `["p1","p2"]`, string(files.files[reports[0].Key]))
+	assert.Equal(t, 11, reports[0].FileSize)
+	assert.Equal(t, "[]", string(files.files[reports[1].Key]))
+	assert.Len(t, files.files, 4, "the failed asset type must not be uploaded")
+	assert.Equal(t, map[string]map[string]string{
+		"Child":  {"p2": "boom"},
+		"Failed": {"Failed": "down"},
+	}, errorMap)
+}
+
+func TestRunCollectorsCancelled(t *testing.T) {
+	registry := NewCollectorRegistry()
+	registry.MustRegister(Collector{Name: "A", Prefix: "A",
+		Fetch: func(_ context.Context, req FetchRequest) CollectorResult {
+			_ = NewJSONArrayWriter(req.Output).Close()
+			return CollectorResult{}
+		}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dc := &dataCollectionService{registry: registry, sink: newFakeSink(), concurrency: 0}
+	errorMap := make(map[string]map[string]string)
+	reports := dc.runCollectors(ctx, "token", "pkey", handlers.JSONFormat, NewFanOutExecutor(1, 0), errorMap)
+	assert.Len(t, reports, 1)
+	assert.Equal(t, "", reports[0].Key)
+	assert.NotEmpty(t, errorMap)
+}
+
+func TestPublishStatusesAndEndOfCollection(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	scheduler := &fakeProducer{}
+	details := handlers.ConsumerDetails{CollectionID: "c1", ApplicationCustomerID: "a1", CollectionType: "Inventory"}
+	reports := []CollectorReport{
+		{Name: "VM", Prefix: "VM", Key: "k/vm", FileSize: 10},
+		{Name: "DS", Prefix: "DS", Errors: map[string]string{"DS": "down"}},
+		{Name: "VMBK", Prefix: "VMBK", Key: "k/bk", FileSize: 5, Errors: map[string]string{"vm-1": "e"}},
+	}
+	publishStatuses(context.Background(), details, reports, scheduler)
+	PublishEndOfCollection(context.Background(), details, newCollectionResult(Partial,
+		sink.Object{Key: "k/manifest", Size: 3}, nil, map[string]map[string]string{"DS": {"DS": "down"}}), scheduler)
+
+	require.Len(t, scheduler.messages, 4)
+	statuses := make([]model.ScheduleStatus, len(scheduler.messages))
+	for i, message := range scheduler.messages {
+		assert.Equal(t, "c1", message.key)
+		require.NoError(t, json.Unmarshal(message.data, &statuses[i]))
+	}
+	assert.Equal(t, model.ScheduleStatus{CollectionID: "c1", ApplicationCustomerID: "a1", CollectionType: "Inventory",
+		HaulerType: Common, DeviceType: "VM", JSONVersion: statuses[0].JSONVersion, UploadStatus: Success,
+		CollectionStatus: Success, UploadFileSize: "10", S3Bucket: statuses[0].S3Bucket, FileName: "k/vm"}, statuses[0])
+	assert.Equal(t, Failed, statuses[1].UploadStatus)
+	assert.Equal(t, Failed, statuses[1].CollectionStatus)
+	assert.JSONEq(t, `{"DS":"down"}`, statuses[1].Error)
+	assert.Equal(t, Success, statuses[2].UploadStatus)
+	assert.Equal(t, Partial, statuses[2].CollectionStatus)
+
+	eoc := statuses[3]
+	assert.Equal(t, handlers.EndOfCollection, eoc.CollectionType)
+	assert.Equal(t, handlers.UnknownDeviceType, eoc.DeviceType)
+	assert.Equal(t, Partial, eoc.CollectionStatus)
+	assert.Equal(t, Success, eoc.UploadStatus)
+	assert.Equal(t, "k/manifest", eoc.FileName)
+	assert.JSONEq(t, `{"DS":{"DS":"down"}}`, eoc.Error)
+	assert.Equal(t, "Inventory", details.CollectionType, "the caller's details are left untouched")
+}
+
+func TestPublishFailedCollection(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	scheduler := &fakeProducer{}
+	details := handlers.ConsumerDetails{CollectionID: "c1", CollectionType: "Inventory"}
+
+	result := PublishFailedCollection(context.Background(), details,
+		map[string]map[string]string{handlers.RESTError: {handlers.RESTError: "no credentials"}}, scheduler)
+	assert.Equal(t, Failed, result.Status)
+
+	require.Len(t, scheduler.messages, 2)
+	statuses := make([]model.ScheduleStatus, len(scheduler.messages))
+	for i, message := range scheduler.messages {
+		require.NoError(t, json.Unmarshal(message.data, &statuses[i]))
+		assert.Equal(t, Failed, statuses[i].CollectionStatus)
+		assert.Equal(t, Failed, statuses[i].UploadStatus)
+		assert.Equal(t, handlers.UnknownDeviceType, statuses[i].DeviceType)
+		assert.JSONEq(t, `{"RESTError":{"RESTError":"no credentials"}}`, statuses[i].Error)
+	}
+	assert.Equal(t, "Inventory", statuses[0].CollectionType)
+	assert.Equal(t, handlers.EndOfCollection, statuses[1].CollectionType)
+}
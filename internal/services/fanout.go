@@ -0,0 +1,67 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+// FanOutExecutor bounds the per asset requests (backups, snapshots) issued by a collection.
+// The worker slots are shared by every collector of the collection.
+type FanOutExecutor struct {
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+func NewFanOutExecutor(concurrency int, timeout time.Duration) *FanOutExecutor {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &FanOutExecutor{
+		slots:   make(chan struct{}, concurrency),
+		timeout: timeout,
+	}
+}
+
+// FanOutExecutorFromConfig returns an executor of the configured concurrency and request timeout.
+func FanOutExecutorFromConfig() *FanOutExecutor {
+	return NewFanOutExecutor(configs.GetFanOutConcurrency(), configs.GetFanOutRequestTimeout())
+}
+
+// FanOut calls do once per ID on the executor's workers, each call bounded by the executor's
+// request timeout. Failures are returned as error messages keyed by ID.
+func FanOut(ctx context.Context, executor *FanOutExecutor, ids []string,
+	do func(ctx context.Context, id string) error) map[string]string {
+	errs := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		if !acquireSlot(ctx, executor.slots) {
+			mu.Lock()
+			errs[id] = ctx.Err().Error()
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-executor.slots }()
+			reqCtx, cancel := ctx, context.CancelFunc(func() {})
+			if executor.timeout > 0 {
+				reqCtx, cancel = context.WithTimeout(ctx, executor.timeout)
+			}
+			defer cancel()
+			if err := do(reqCtx, id); err != nil {
+				mu.Lock()
+				errs[id] = collectionError(ctx, err)
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,76 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+func TestFanOut(t *testing.T) {
+	var active, maxActive int32
+	executor := NewFanOutExecutor(2, 50*time.Millisecond)
+	var mu sync.Mutex
+	done := make(map[string]bool)
+	errs := FanOut(context.Background(), executor, []string{"a", "b", "c", "slow", "bad"},
+		func(ctx context.Context, id string) error {
+			n := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			for m := atomic.LoadInt32(&maxActive); n > m; m = atomic.LoadInt32(&maxActive) {
+				if atomic.CompareAndSwapInt32(&maxActive, m, n) {
+					break
+				}
+			}
+			switch id {
+			case "slow":
+				<-ctx.Done()
+				return ctx.Err()
+			case "bad":
+				return errors.New("bad request")
+			}
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			done[id] = true
+			return nil
+		})
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxActive), int32(2))
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, done)
+	assert.Equal(t, map[string]string{
+		"slow": context.DeadlineExceeded.Error(),
+		"bad":  "bad request",
+	}, errs)
+}
+
+func TestFanOutCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	errs := FanOut(ctx, NewFanOutExecutor(1, 0), []string{"a", "b"},
+		func(ctx context.Context, id string) error {
+			called = true
+			return nil
+		})
+	assert.False(t, called)
+	assert.Len(t, errs, 2)
+}
+
+func TestFanOutExecutorScopedToCollection(t *testing.T) {
+	first, ok := NewDataCollectionService(context.Background(), nil, nil).(*dataCollectionService)
+	require.True(t, ok)
+	second, ok := NewDataCollectionService(context.Background(), nil, nil).(*dataCollectionService)
+	require.True(t, ok)
+	require.NotNil(t, first.fanOut)
+	assert.NotSame(t, first.fanOut, second.fanOut, "executors are released with their collection")
+	assert.Equal(t, configs.GetFanOutConcurrency(), cap(first.fanOut.slots))
+}
@@ -0,0 +1,80 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
+)
+
+// HarmonyError is the key of the failed file notifications in the error map of an asset type.
+const HarmonyError = "HarmonyError"
+
+// HarmonyRetryPolicy controls how file notifications failing to publish are retried.
+type HarmonyRetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on every following retry.
+	BaseDelay time.Duration
+}
+
+// HarmonyRetryPolicyFromConfig builds the retry policy of the file notifications.
+func HarmonyRetryPolicyFromConfig() HarmonyRetryPolicy {
+	baseDelay, err := time.ParseDuration(configs.GetRetryBackoff())
+	if err != nil {
+		log.Errorf("Invalid retry backoff %q, notifications are not delayed : %v", configs.GetRetryBackoff(), err)
+	}
+	return HarmonyRetryPolicy{MaxAttempts: configs.GetHarmonyMaxAttempts(), BaseDelay: baseDelay}
+}
+
+// publish calls publishFn until it succeeds, the attempts are exhausted or ctx is done.
+func (p HarmonyRetryPolicy) publish(ctx context.Context, publishFn func() error) error {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := publishFn()
+		if err == nil || attempt >= p.MaxAttempts {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// notifyUploads announces every uploaded asset file on the Harmony topic, with the prefix of its
+// asset type as file type. Notifications still failing once retried are recorded in the report
+// and in the error map, so the asset type is reported as partially collected.
+func (dc *dataCollectionService) notifyUploads(ctx context.Context, consumerDetails handlers.ConsumerDetails,
+	reports []CollectorReport, errorMap map[string]map[string]string, harmonyProducer producer.Producer) {
+	for i := range reports {
+		report := &reports[i]
+		if report.Key == "" {
+			continue
+		}
+		fileType := handlers.FileType(report.Prefix, consumerDetails.OutputFormat)
+		err := dc.harmonyRetry.publish(ctx, func() error {
+			return handlers.PublishtoHarmonyKafka(ctx, consumerDetails, fileType, report.Key, dc.sink.Location(),
+				report.FileSize, harmonyProducer)
+		})
+		if err == nil {
+			continue
+		}
+		log.WithContext(ctx).Errorf("%s notification of %s failed : %v", report.Name, report.Key, err)
+		errs := make(map[string]string, len(report.Errors)+1)
+		for k, v := range report.Errors {
+			errs[k] = v
+		}
+		errs[HarmonyError] = err.Error()
+		report.Errors = errs
+		handlers.SetNested(errorMap, report.Name, HarmonyError, err.Error())
+	}
+}
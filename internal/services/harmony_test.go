@@ -0,0 +1,77 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+func TestHarmonyRetryPolicy(t *testing.T) {
+	policy := HarmonyRetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	attempts := 0
+	err := policy.publish(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return errPublish
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = policy.publish(context.Background(), func() error {
+		attempts++
+		return errPublish
+	})
+	assert.ErrorIs(t, err, errPublish)
+	assert.Equal(t, 3, attempts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = HarmonyRetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}.publish(ctx, func() error {
+		attempts++
+		return errPublish
+	})
+	assert.ErrorIs(t, err, errPublish)
+	assert.Equal(t, 1, attempts, "no retry once the collection is cancelled")
+}
+
+func TestNotifyUploads(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	harmony := &fakeProducer{failures: 1}
+	dc := &dataCollectionService{sink: newFakeSink(), harmonyRetry: HarmonyRetryPolicy{MaxAttempts: 2}}
+	details := handlers.ConsumerDetails{CollectionID: "c1", OutputFormat: handlers.NDJSONFormat}
+	reports := []CollectorReport{
+		{Name: VirtualMachines, Prefix: "VM", Key: "Fleet/VM/a.ndjson", FileSize: 10},
+		{Name: Datastores, Prefix: "DS", Errors: map[string]string{Datastores: "down"}},
+		{Name: MssqlDB, Prefix: "MSSQL-DB", Key: "Fleet/MSSQL-DB/a.ndjson", FileSize: 7},
+	}
+	errorMap := make(map[string]map[string]string)
+	dc.notifyUploads(context.Background(), details, reports, errorMap, harmony)
+
+	require.Len(t, harmony.messages, 2, "the failed notification is retried, nothing is sent for failed uploads")
+	var notification model.HarmonyStatusRequest
+	require.NoError(t, json.Unmarshal(harmony.messages[1].data, &notification))
+	assert.Equal(t, "MSSQL-DB-NDJSON", notification.Notification.FileType)
+	assert.Equal(t, "Fleet/MSSQL-DB/a.ndjson", notification.Source.S3.Key)
+	assert.Equal(t, 7, notification.Notification.FileSize)
+	assert.Contains(t, notification.Notification.EntityID, "c1_")
+	assert.Empty(t, errorMap)
+
+	harmony = &fakeProducer{failures: 2}
+	dc.notifyUploads(context.Background(), details, reports[:1], errorMap, harmony)
+	assert.Empty(t, harmony.messages)
+	assert.Equal(t, errPublish.Error(), errorMap[VirtualMachines][HarmonyError])
+	assert.Equal(t, Partial, reports[0].Status())
+}
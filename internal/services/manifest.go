@@ -0,0 +1,140 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/sink"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
+)
+
+// ManifestPrefix is the object prefix and Harmony file type of the collection manifests.
+const ManifestPrefix = "MANIFEST"
+
+// Manifest ties the files of a collection together. It is written once every asset type is
+// uploaded, so its presence tells consumers the collection is complete.
+type Manifest struct {
+	CollectionID          string          `json:"collectionId"`
+	PlatformCustomerID    string          `json:"platformCustomerId"`
+	ApplicationCustomerID string          `json:"applicationCustomerId"`
+	StartTime             time.Time       `json:"startTime"`
+	EndTime               time.Time       `json:"endTime"`
+	Status                string          `json:"status"`
+	Format                string          `json:"format"`
+	Assets                []ManifestAsset `json:"assets"`
+	// Errors holds the failures not tied to an asset type, e.g. the authentication.
+	Errors map[string]map[string]string `json:"errors,omitempty"`
+}
+
+// ManifestAsset describes the file of a single asset type. Key, ByteSize and SHA256 are
+// empty when nothing was uploaded.
+type ManifestAsset struct {
+	Name     string            `json:"name"`
+	Prefix   string            `json:"prefix"`
+	Key      string            `json:"key,omitempty"`
+	Count    int               `json:"count"`
+	ByteSize int64             `json:"byteSize"`
+	SHA256   string            `json:"sha256,omitempty"`
+	Status   string            `json:"status"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// Status returns Failed when the asset type could not be fetched or uploaded, Partial when some
+// of its assets failed and Success otherwise.
+func (r CollectorReport) Status() string {
+	if _, failed := r.Errors[r.Name]; failed || r.Err != nil {
+		return Failed
+	}
+	if len(r.Errors) > 0 {
+		return Partial
+	}
+	return Success
+}
+
+// NewManifest builds the manifest of a collection from the reports of its collectors.
+func NewManifest(consumerDetails handlers.ConsumerDetails, start, end time.Time, reports []CollectorReport,
+	errorMap map[string]map[string]string) Manifest {
+	manifest := Manifest{
+		CollectionID:          consumerDetails.CollectionID,
+		PlatformCustomerID:    consumerDetails.PlatformCustomerID,
+		ApplicationCustomerID: consumerDetails.ApplicationCustomerID,
+		StartTime:             start,
+		EndTime:               end,
+		Format:                consumerDetails.OutputFormat,
+		Assets:                make([]ManifestAsset, 0, len(reports)),
+	}
+	names := make(map[string]bool, len(reports))
+	statuses := make(map[string]int)
+	for _, report := range reports {
+		asset := ManifestAsset{
+			Name:     report.Name,
+			Prefix:   report.Prefix,
+			Key:      report.Key,
+			Count:    report.Count,
+			ByteSize: int64(report.FileSize),
+			SHA256:   report.SHA256,
+			Status:   report.Status(),
+			Errors:   report.Errors,
+		}
+		if report.Err != nil {
+			asset.Errors = map[string]string{report.Name: report.Err.Error()}
+		}
+		names[report.Name] = true
+		statuses[asset.Status]++
+		manifest.Assets = append(manifest.Assets, asset)
+	}
+	for name, errs := range errorMap {
+		if !names[name] {
+			if manifest.Errors == nil {
+				manifest.Errors = make(map[string]map[string]string)
+			}
+			manifest.Errors[name] = errs
+		}
+	}
+	switch {
+	case len(manifest.Errors) == 0 && statuses[Success] == len(reports):
+		manifest.Status = Success
+	case statuses[Failed] == len(reports):
+		manifest.Status = Failed
+	default:
+		manifest.Status = Partial
+	}
+	return manifest
+}
+
+// manifestKey returns the key of the manifest of the collection whose files are named pKey.
+func manifestKey(pKey, format string) string {
+	return configs.GetSourceType() + "/" + ManifestPrefix + "/" + strings.TrimSuffix(pKey, fileExtension(format)) +
+		jsonExt
+}
+
+// publishManifest stores the manifest and announces it on the Harmony topic.
+func (dc *dataCollectionService) publishManifest(ctx context.Context, consumerDetails handlers.ConsumerDetails,
+	manifest Manifest, key string, harmonyProducer producer.Producer) (sink.Object, error) {
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Manifest encoding failed : %v", err)
+		return sink.Object{}, err
+	}
+	object, err := dc.sink.Put(ctx, key, bytes.NewReader(content), sink.Attributes{ContentType: sink.JSONContentType})
+	if err != nil {
+		log.WithContext(ctx).Errorf("Manifest upload failed : %v", err)
+		return sink.Object{}, err
+	}
+	log.WithContext(ctx).Infof("Manifest of collection %v uploaded to %v with status %v", manifest.CollectionID,
+		object.Key, manifest.Status)
+	err = dc.harmonyRetry.publish(ctx, func() error {
+		return handlers.PublishtoHarmonyKafka(ctx, consumerDetails, ManifestPrefix, object.Key, dc.sink.Location(),
+			int(object.Size), harmonyProducer)
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("Manifest notification of %v failed : %v", object.Key, err)
+	}
+	return object, nil
+}
@@ -0,0 +1,115 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+var errPublish = errors.New("broker unavailable")
+
+type publishedMessage struct {
+	key, customerID string
+	data            []byte
+}
+
+type fakeProducer struct {
+	mu       sync.Mutex
+	messages []publishedMessage
+	// failures is the number of publications failing before the next ones succeed
+	failures int
+}
+
+func (p *fakeProducer) CloseWriter() error {
+	return nil
+}
+
+func (p *fakeProducer) PublishMessage(_ context.Context, key, customerID, _, _ string, _ time.Time,
+	data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errPublish
+	}
+	p.messages = append(p.messages, publishedMessage{key: key, customerID: customerID, data: data})
+	return nil
+}
+
+func TestCollectorReportStatus(t *testing.T) {
+	assert.Equal(t, Success, CollectorReport{Name: "VM"}.Status())
+	assert.Equal(t, Partial, CollectorReport{Name: "VM", Errors: map[string]string{"vm-1": "e"}}.Status())
+	assert.Equal(t, Failed, CollectorReport{Name: "VM", Errors: map[string]string{"VM": "e"}}.Status())
+	assert.Equal(t, Failed, CollectorReport{Name: "VM", Err: errors.New("upload")}.Status())
+}
+
+func TestNewManifest(t *testing.T) {
+	details := handlers.ConsumerDetails{CollectionID: "c1", PlatformCustomerID: "p1", ApplicationCustomerID: "a1",
+		OutputFormat: handlers.NDJSONFormat}
+	start, end := time.Unix(100, 0).UTC(), time.Unix(200, 0).UTC()
+	reports := []CollectorReport{
+		{Name: "VM", Prefix: "VM", Key: "k/vm", Count: 2, FileSize: 10, SHA256: "abc"},
+		{Name: "DS", Prefix: "DS", Errors: map[string]string{"DS": "down"}},
+	}
+	errorMap := map[string]map[string]string{"DS": {"DS": "down"}}
+
+	manifest := NewManifest(details, start, end, reports, errorMap)
+	assert.Equal(t, "c1", manifest.CollectionID)
+	assert.Equal(t, "p1", manifest.PlatformCustomerID)
+	assert.Equal(t, "a1", manifest.ApplicationCustomerID)
+	assert.Equal(t, start, manifest.StartTime)
+	assert.Equal(t, end, manifest.EndTime)
+	assert.Equal(t, Partial, manifest.Status)
+	assert.Empty(t, manifest.Errors, "asset errors are reported with their asset type")
+	assert.Equal(t, []ManifestAsset{
+		{Name: "VM", Prefix: "VM", Key: "k/vm", Count: 2, ByteSize: 10, SHA256: "abc", Status: Success},
+		{Name: "DS", Prefix: "DS", Status: Failed, Errors: map[string]string{"DS": "down"}},
+	}, manifest.Assets)
+
+	assert.Equal(t, Success, NewManifest(details, start, end, reports[:1], nil).Status)
+	assert.Equal(t, Failed, NewManifest(details, start, end, reports[1:], errorMap).Status)
+
+	authFailed := NewManifest(details, start, end, nil, map[string]map[string]string{AuthError: {AuthError: "e"}})
+	assert.Equal(t, Failed, authFailed.Status)
+	assert.Equal(t, map[string]map[string]string{AuthError: {AuthError: "e"}}, authFailed.Errors)
+	assert.NotNil(t, authFailed.Assets)
+}
+
+func TestPublishManifest(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	fileSink := newFakeSink()
+	harmony := &fakeProducer{}
+	dc := &dataCollectionService{sink: fileSink}
+	details := handlers.ConsumerDetails{CollectionID: "c1", ApplicationCustomerID: "a1"}
+	key := manifestKey("partitionKey=1/x.ndjson", handlers.NDJSONFormat)
+	assert.Equal(t, configs.GetSourceType()+"/MANIFEST/partitionKey=1/x.json", key)
+
+	manifest := NewManifest(details, time.Now(), time.Now(), []CollectorReport{{Name: "VM", Prefix: "VM"}}, nil)
+	dc.publishManifest(context.Background(), details, manifest, key, harmony)
+
+	var stored Manifest
+	require.NoError(t, json.Unmarshal(fileSink.files[key], &stored))
+	assert.Equal(t, "c1", stored.CollectionID)
+	assert.Equal(t, Success, stored.Status)
+	require.Len(t, stored.Assets, 1)
+
+	require.Len(t, harmony.messages, 1)
+	assert.Equal(t, "c1", harmony.messages[0].key)
+	var notification model.HarmonyStatusRequest
+	require.NoError(t, json.Unmarshal(harmony.messages[0].data, &notification))
+	assert.Equal(t, ManifestPrefix, notification.Notification.FileType)
+	assert.Equal(t, key, notification.Source.S3.Key)
+	assert.Equal(t, len(fileSink.files[key]), notification.Notification.FileSize)
+}
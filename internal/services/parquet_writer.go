@@ -0,0 +1,238 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/segmentio/parquet-go"
+)
+
+const (
+	// parquetColumnSeparator joins the names of nested struct fields into a column name,
+	// e.g. computeInfo_numCpuCores.
+	parquetColumnSeparator = "_"
+	// parquetRowGroupSize bounds the rows buffered in memory before a row group is flushed.
+	parquetRowGroupSize = 10000
+)
+
+var errDuplicateColumn = errors.New("duplicate parquet column")
+
+type parquetKind int
+
+const (
+	parquetString parquetKind = iota
+	parquetBool
+	parquetInt
+	parquetUint
+	parquetFloat
+	// parquetJSON columns hold the JSON encoding of slices, maps and interfaces.
+	parquetJSON
+)
+
+// parquetColumn maps a leaf field of the model to a column of the flat schema.
+type parquetColumn struct {
+	name string
+	// field is the index path of the leaf in the model, nil for the parent ID column.
+	field    []int
+	kind     parquetKind
+	optional bool
+	index    int
+}
+
+// parquetEncoder writes models of a single type as rows of a flat Parquet schema. Nested
+// structs are flattened into one column per leaf field, named after the JSON names of the
+// fields on the path, and every schema gets an optional parentId column for child assets.
+type parquetEncoder struct {
+	writer  *parquet.Writer
+	columns []parquetColumn
+}
+
+func newParquetEncoder(out io.Writer, model reflect.Type) (*parquetEncoder, error) {
+	for model.Kind() == reflect.Ptr {
+		model = model.Elem()
+	}
+	columns := []parquetColumn{{name: parentIDField, kind: parquetString, optional: true}}
+	if model.Kind() == reflect.Struct {
+		columns = flattenStruct(model, "", nil, columns)
+	} else {
+		columns = append(columns, parquetColumn{name: "value", field: []int{}, kind: parquetKindOf(model)})
+	}
+
+	group := make(parquet.Group, len(columns))
+	for _, column := range columns {
+		if _, found := group[column.name]; found {
+			return nil, fmt.Errorf("%w: %s", errDuplicateColumn, column.name)
+		}
+		group[column.name] = column.node()
+	}
+	schema := parquet.NewSchema(model.Name(), group)
+	// the schema orders the columns by name
+	indexes := make(map[string]int)
+	for i, path := range schema.Columns() {
+		indexes[strings.Join(path, ".")] = i
+	}
+	ordered := make([]parquetColumn, len(columns))
+	for _, column := range columns {
+		column.index = indexes[column.name]
+		ordered[column.index] = column
+	}
+	return &parquetEncoder{
+		writer: parquet.NewWriter(out, schema, parquet.Compression(&parquet.Snappy),
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+		columns: ordered,
+	}, nil
+}
+
+func flattenStruct(model reflect.Type, prefix string, path []int, columns []parquetColumn) []parquetColumn {
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		name := jsonName(field)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		fieldPath := append(append([]int(nil), path...), i)
+		if field.Type.Kind() == reflect.Struct {
+			// embedded structs are inlined like in the JSON encoding
+			nested := prefix + name + parquetColumnSeparator
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				nested = prefix
+			}
+			columns = flattenStruct(field.Type, nested, fieldPath, columns)
+			continue
+		}
+		column := parquetColumn{name: prefix + name, field: fieldPath, kind: parquetKindOf(field.Type)}
+		column.optional = column.kind == parquetJSON || field.Type.Kind() == reflect.Ptr
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// jsonName returns the name of a field in the JSON encoding of the model, empty when skipped.
+func jsonName(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return tag
+	}
+}
+
+func parquetKindOf(t reflect.Type) parquetKind {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return parquetString
+	case reflect.Bool:
+		return parquetBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return parquetInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return parquetUint
+	case reflect.Float32, reflect.Float64:
+		return parquetFloat
+	default:
+		return parquetJSON
+	}
+}
+
+func (c parquetColumn) node() parquet.Node {
+	var node parquet.Node
+	switch c.kind {
+	case parquetBool:
+		node = parquet.Leaf(parquet.BooleanType)
+	case parquetInt, parquetUint:
+		node = parquet.Int(64)
+	case parquetFloat:
+		node = parquet.Leaf(parquet.DoubleType)
+	default:
+		node = parquet.String()
+	}
+	if c.optional {
+		return parquet.Optional(node)
+	}
+	return node
+}
+
+// value returns the value of the column for item, a null value for unset optional columns.
+func (c parquetColumn) value(item reflect.Value, parentID string) (parquet.Value, error) {
+	if c.field == nil {
+		if parentID == "" {
+			return parquet.Value{}.Level(0, 0, c.index), nil
+		}
+		return parquet.ByteArrayValue([]byte(parentID)).Level(0, 1, c.index), nil
+	}
+	field := item.FieldByIndex(c.field)
+	if c.kind != parquetJSON && field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return parquet.Value{}.Level(0, 0, c.index), nil
+		}
+		field = field.Elem()
+	}
+	var value parquet.Value
+	switch c.kind {
+	case parquetString:
+		value = parquet.ByteArrayValue([]byte(field.String()))
+	case parquetBool:
+		value = parquet.BooleanValue(field.Bool())
+	case parquetInt:
+		value = parquet.Int64Value(field.Int())
+	case parquetUint:
+		value = parquet.Int64Value(int64(field.Uint()))
+	case parquetFloat:
+		value = parquet.DoubleValue(field.Float())
+	default:
+		if isNil(field) {
+			return parquet.Value{}.Level(0, 0, c.index), nil
+		}
+		content, err := json.Marshal(field.Interface())
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		value = parquet.ByteArrayValue(content)
+	}
+	if c.optional {
+		return value.Level(0, 1, c.index), nil
+	}
+	return value.Level(0, 0, c.index), nil
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// write adds item as a row, with parentID in the parentId column when set.
+func (e *parquetEncoder) write(item interface{}, parentID string) error {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	row := make(parquet.Row, len(e.columns))
+	for i, column := range e.columns {
+		value, err := column.value(v, parentID)
+		if err != nil {
+			return err
+		}
+		row[i] = value
+	}
+	_, err := e.writer.WriteRows([]parquet.Row{row})
+	return err
+}
+
+func (e *parquetEncoder) close() error {
+	return e.writer.Close()
+}
@@ -0,0 +1,110 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+)
+
+type parquetItem struct {
+	ID      string            `json:"id"`
+	Size    *int64            `json:"size"`
+	Healthy bool              `json:"healthy"`
+	Ratio   float64           `json:"ratio"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels"`
+	Skipped string            `json:"-"`
+	Info    struct {
+		Cores int `json:"cores"`
+	} `json:"info"`
+}
+
+// readParquet returns the rows of a Parquet file keyed by column name.
+func readParquet(t *testing.T, content []byte) []map[string]parquet.Value {
+	file, err := parquet.OpenFile(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	columns := file.Schema().Columns()
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+
+	var rows []map[string]parquet.Value
+	buffer := make([]parquet.Row, 10)
+	for {
+		n, err := reader.ReadRows(buffer)
+		for _, row := range buffer[:n] {
+			values := make(map[string]parquet.Value)
+			for _, value := range row {
+				values[columns[value.Column()][0]] = value
+			}
+			rows = append(rows, values)
+		}
+		if err == io.EOF {
+			return rows
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewParquetWriter(&out, reflect.TypeOf(parquetItem{}))
+	size := int64(42)
+	first := parquetItem{ID: "a", Size: &size, Healthy: true, Ratio: 0.5, Tags: []string{"x"}, Skipped: "s"}
+	first.Info.Cores = 4
+	require.NoError(t, WriteItems(writer, []parquetItem{first}))
+	require.NoError(t, WriteChildItems(writer, "vm-1", []parquetItem{{ID: "b"}}))
+	require.NoError(t, WriteGroup(writer, "vm-2", []parquetItem{{ID: "c"}}))
+	require.NoError(t, writer.Close())
+	assert.Equal(t, 3, writer.Count())
+
+	rows := readParquet(t, out.Bytes())
+	require.Len(t, rows, 3)
+	assert.Equal(t, "a", rows[0]["id"].String())
+	assert.True(t, rows[0][parentIDField].IsNull())
+	assert.Equal(t, int64(42), rows[0]["size"].Int64())
+	assert.True(t, rows[0]["healthy"].Boolean())
+	assert.Equal(t, 0.5, rows[0]["ratio"].Double())
+	assert.Equal(t, `["x"]`, rows[0]["tags"].String())
+	assert.True(t, rows[0]["labels"].IsNull())
+	assert.Equal(t, int64(4), rows[0]["info_cores"].Int64())
+	assert.NotContains(t, rows[0], "Skipped")
+
+	assert.Equal(t, "vm-1", rows[1][parentIDField].String())
+	assert.True(t, rows[1]["size"].IsNull())
+	assert.Equal(t, "vm-2", rows[2][parentIDField].String())
+}
+
+func TestParquetWriterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, NewParquetWriter(&out, reflect.TypeOf(parquetItem{})).Close())
+	assert.Empty(t, readParquet(t, out.Bytes()), "an empty file is still written")
+}
+
+func TestParquetWriterFlattensModels(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewParquetWriter(&out, reflect.TypeOf(model.VirtualMachine{}))
+	vm := model.VirtualMachine{ID: "vm-1"}
+	vm.ComputeInfo.NumCPUCores = 8
+	require.NoError(t, WriteItems(writer, []model.VirtualMachine{vm}))
+	require.NoError(t, writer.Close())
+
+	rows := readParquet(t, out.Bytes())
+	require.Len(t, rows, 1)
+	assert.Equal(t, "vm-1", rows[0]["id"].String())
+	assert.Equal(t, int64(8), rows[0]["computeInfo_numCpuCores"].Int64())
+
+	for _, m := range []interface{}{model.CSPMachineInstance{}, model.CSPVolume{}, model.MsSqlDB{},
+		model.Datastore{}, model.ProtectionPolicy{}, model.ZertoVPG{}} {
+		_, err := newParquetEncoder(io.Discard, reflect.TypeOf(m))
+		assert.NoError(t, err, "%T", m)
+	}
+}
@@ -0,0 +1,289 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/sink"
+)
+
+// streamBufferSize is the size of the writes handed to the upload pipe.
+const streamBufferSize = 64 * 1024
+
+var errWriterClosed = errors.New("stream writer is closed")
+
+// StreamWriter encodes the assets of a collector as they are fetched, either as a single
+// JSON array of items, as a JSON object of item arrays keyed by parent asset ID, as newline
+// delimited JSON with one asset per line, or as the rows of a Parquet file. It is safe for
+// concurrent use, the items of a single write are kept together.
+type StreamWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	open    []byte
+	close   []byte
+	ndjson  bool
+	object  bool
+	parquet *parquetEncoder
+	// err is the failure to set up the writer, returned by every write
+	err     error
+	started bool
+	members int
+	count   int
+	closed  bool
+}
+
+// NewJSONArrayWriter returns a writer encoding the items written with WriteItems as one JSON array.
+func NewJSONArrayWriter(out io.Writer) *StreamWriter {
+	return &StreamWriter{out: out, open: []byte("["), close: []byte("]")}
+}
+
+// NewJSONObjectWriter returns a writer encoding the groups written with WriteGroup as one JSON object.
+func NewJSONObjectWriter(out io.Writer) *StreamWriter {
+	return &StreamWriter{out: out, open: []byte("{"), close: []byte("}"), object: true}
+}
+
+// NewNDJSONWriter returns a writer encoding every item on its own line. The items of a parent
+// asset get its ID inlined in a "parentId" member.
+func NewNDJSONWriter(out io.Writer) *StreamWriter {
+	return &StreamWriter{out: out, open: []byte{}, close: []byte{}, ndjson: true}
+}
+
+// NewParquetWriter returns a writer encoding the items written with WriteItems as the rows of a
+// Parquet file whose flat schema is derived from model, see parquetEncoder. The items of a
+// parent asset get its ID in the parentId column.
+func NewParquetWriter(out io.Writer, model reflect.Type) *StreamWriter {
+	encoder, err := newParquetEncoder(out, model)
+	return &StreamWriter{out: out, parquet: encoder, err: err}
+}
+
+// Keyed reports whether the writer encodes a JSON object, which needs the items of a group together.
+func (w *StreamWriter) Keyed() bool {
+	return w.object
+}
+
+// Count returns the number of items written so far.
+func (w *StreamWriter) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Close terminates the document. Nothing is written to the output before the first items,
+// so a writer left unclosed after a failed fetch may have written nothing at all.
+func (w *StreamWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	if w.parquet != nil {
+		return w.parquet.close()
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+	_, err := w.out.Write(w.close)
+	return err
+}
+
+// begin writes the opening of the document. It is written even when empty so the output
+// sees a write for every started document.
+func (w *StreamWriter) begin() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	_, err := w.out.Write(w.open)
+	return err
+}
+
+// separator writes the comma preceding every member but the first.
+func (w *StreamWriter) separator() error {
+	w.members++
+	if w.members == 1 || w.ndjson {
+		return nil
+	}
+	_, err := w.out.Write([]byte{','})
+	return err
+}
+
+// writeValue writes a member, on its own line for NDJSON.
+func (w *StreamWriter) writeValue(content []byte) error {
+	if err := w.separator(); err != nil {
+		return err
+	}
+	if w.ndjson {
+		content = append(content, '\n')
+	}
+	_, err := w.out.Write(content)
+	return err
+}
+
+// writeItems appends items, with parentID inlined for NDJSON and Parquet when set.
+func writeItems[T any](w *StreamWriter, parentID string, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errWriterClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.parquet != nil {
+		for i := range items {
+			if err := w.parquet.write(items[i], parentID); err != nil {
+				return err
+			}
+		}
+		w.count += len(items)
+		return nil
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+	for i := range items {
+		content, err := json.Marshal(&items[i])
+		if err != nil {
+			return err
+		}
+		if w.ndjson && parentID != "" {
+			content = inlineParentID(content, parentID)
+		}
+		if err = w.writeValue(content); err != nil {
+			return err
+		}
+	}
+	w.count += len(items)
+	return nil
+}
+
+// WriteItems appends items to an array, NDJSON or Parquet writer.
+func WriteItems[T any](w *StreamWriter, items []T) error {
+	return writeItems(w, "", items)
+}
+
+// WriteChildItems appends the items of a parent asset to an array, NDJSON or Parquet writer. The
+// parent ID is inlined in NDJSON lines and Parquet rows only, array items are written as they are.
+func WriteChildItems[T any](w *StreamWriter, parentID string, items []T) error {
+	return writeItems(w, parentID, items)
+}
+
+// WriteGroup adds the items of a parent asset to the JSON object of an object writer. NDJSON
+// and Parquet writers write the items on their own lines or rows with the parent ID inlined instead.
+func WriteGroup[T any](w *StreamWriter, key string, items []T) error {
+	if w.ndjson || w.parquet != nil || w.err != nil {
+		return writeItems(w, key, items)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errWriterClosed
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+	if items == nil {
+		items = []T{}
+	}
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	encodedItems, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	member := append(append(encodedKey, ':'), encodedItems...)
+	if err = w.writeValue(member); err != nil {
+		return err
+	}
+	w.count += len(items)
+	return nil
+}
+
+// parentIDField is the member holding the parent asset ID in NDJSON lines and Parquet rows.
+const parentIDField = "parentId"
+
+// inlineParentID adds the parent ID as first member of an encoded JSON object. Other values
+// are returned unchanged.
+func inlineParentID(content []byte, parentID string) []byte {
+	if len(content) < 2 || content[0] != '{' {
+		return content
+	}
+	member, _ := json.Marshal(parentID)
+	inlined := make([]byte, 0, len(content)+len(parentIDField)+len(member)+4)
+	inlined = append(inlined, `{"`+parentIDField+`":`...)
+	inlined = append(inlined, member...)
+	if content[1] != '}' {
+		inlined = append(inlined, ',')
+	}
+	return append(inlined, content[1:]...)
+}
+
+// streamUpload pipes what a collector writes into a sink upload, started on the first write
+// so asset types failing before their first page leave nothing behind.
+type streamUpload struct {
+	ctx         context.Context
+	sink        sink.Sink
+	key         string
+	contentType string
+	pipe        *io.PipeWriter
+	buffer      *bufio.Writer
+	done        chan struct{}
+	object      sink.Object
+	err         error
+}
+
+func newStreamUpload(ctx context.Context, fileSink sink.Sink, key, contentType string) *streamUpload {
+	return &streamUpload{ctx: ctx, sink: fileSink, key: key, contentType: contentType}
+}
+
+func (u *streamUpload) Write(p []byte) (int, error) {
+	if u.pipe == nil {
+		u.start()
+	}
+	return u.buffer.Write(p)
+}
+
+func (u *streamUpload) start() {
+	reader, writer := io.Pipe()
+	u.pipe = writer
+	u.buffer = bufio.NewWriterSize(writer, streamBufferSize)
+	u.done = make(chan struct{})
+	go func() {
+		defer close(u.done)
+		u.object, u.err = u.sink.Put(u.ctx, u.key, reader, sink.Attributes{ContentType: u.contentType})
+		// unblock the writer when the upload stopped reading early
+		reader.CloseWithError(u.err)
+	}()
+}
+
+// finish completes the upload, or aborts it when abort is set. It reports whether an upload
+// was started and returns the stored object.
+func (u *streamUpload) finish(abort error) (bool, sink.Object, error) {
+	if u.pipe == nil {
+		return false, sink.Object{}, nil
+	}
+	if abort == nil {
+		abort = u.buffer.Flush()
+	}
+	u.pipe.CloseWithError(abort)
+	<-u.done
+	if abort != nil {
+		return true, sink.Object{}, abort
+	}
+	return true, u.object, u.err
+}
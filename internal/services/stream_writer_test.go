@@ -0,0 +1,127 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/sink"
+)
+
+type item struct {
+	ID string `json:"id"`
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewJSONArrayWriter(&out)
+	require.NoError(t, WriteItems(writer, []item{{ID: "a"}, {ID: "b"}}))
+	require.NoError(t, WriteItems(writer, []item{}))
+	require.NoError(t, WriteItems(writer, []item{{ID: "c"}}))
+	require.NoError(t, writer.Close())
+	assert.JSONEq(t, `[{"id":"a"},{"id":"b"},{"id":"c"}]`, out.String())
+	assert.Equal(t, 3, writer.Count())
+	assert.ErrorIs(t, WriteItems(writer, []item{{ID: "d"}}), errWriterClosed)
+
+	out.Reset()
+	writer = NewJSONArrayWriter(&out)
+	assert.Empty(t, out.String(), "nothing is written before the first items")
+	require.NoError(t, writer.Close())
+	assert.Equal(t, "[]", out.String())
+}
+
+func TestJSONObjectWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewJSONObjectWriter(&out)
+	require.NoError(t, WriteGroup(writer, "vm-1", []item{{ID: "a"}}))
+	require.NoError(t, WriteGroup[item](writer, "vm-2", nil))
+	require.NoError(t, writer.Close())
+	assert.JSONEq(t, `{"vm-1":[{"id":"a"}],"vm-2":[]}`, out.String())
+	assert.Equal(t, 1, writer.Count())
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewNDJSONWriter(&out)
+	require.NoError(t, WriteItems(writer, []item{{ID: "a"}}))
+	require.NoError(t, WriteChildItems(writer, "vm-1", []item{{ID: "b"}}))
+	require.NoError(t, WriteGroup(writer, "vm-2", []item{{ID: "c"}, {ID: "d"}}))
+	require.NoError(t, WriteGroup(writer, "vm-3", []struct{}{{}}))
+	require.NoError(t, writer.Close())
+	assert.Equal(t, `{"id":"a"}
+{"parentId":"vm-1","id":"b"}
+{"parentId":"vm-2","id":"c"}
+{"parentId":"vm-2","id":"d"}
+{"parentId":"vm-3"}
+`, out.String())
+	assert.Equal(t, 5, writer.Count())
+
+	var empty countingWriter
+	require.NoError(t, NewNDJSONWriter(&empty).Close())
+	assert.Equal(t, 2, empty.writes, "an empty document is still written")
+}
+
+type countingWriter struct {
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return len(p), nil
+}
+
+func TestStreamUpload(t *testing.T) {
+	files := newFakeSink()
+	ctx := context.Background()
+
+	upload := newStreamUpload(ctx, files, "VM/a.json", sink.JSONContentType)
+	started, object, err := upload.finish(nil)
+	assert.False(t, started)
+	assert.NoError(t, err)
+	assert.Zero(t, object)
+	assert.Empty(t, files.files)
+
+	upload = newStreamUpload(ctx, files, "VM/b.json", sink.JSONContentType)
+	// larger than the pipe buffer so the upload has to consume while the writer runs
+	content := bytes.Repeat([]byte("x"), 3*streamBufferSize)
+	_, err = upload.Write(content)
+	require.NoError(t, err)
+	started, object, err = upload.finish(nil)
+	assert.True(t, started)
+	assert.NoError(t, err)
+	assert.Equal(t, sink.Object{Key: "VM/b.json", Size: int64(len(content))}, object)
+	assert.Equal(t, content, files.files["VM/b.json"])
+
+	upload = newStreamUpload(ctx, files, "VM/c.json", sink.JSONContentType)
+	_, err = upload.Write([]byte("[1,"))
+	require.NoError(t, err)
+	abort := errors.New("fetch failed")
+	_, _, err = upload.finish(abort)
+	assert.ErrorIs(t, err, abort)
+	assert.NotContains(t, files.files, "VM/c.json")
+}
+
+func TestStreamUploadSinkFailure(t *testing.T) {
+	upload := newStreamUpload(context.Background(), failingSink{}, "VM/a.json", sink.JSONContentType)
+	_, err := upload.Write(bytes.Repeat([]byte("x"), 2*streamBufferSize))
+	assert.Error(t, err)
+	_, _, err = upload.finish(nil)
+	assert.Error(t, err)
+}
+
+type failingSink struct{}
+
+func (failingSink) Put(context.Context, string, io.Reader, sink.Attributes) (sink.Object, error) {
+	return sink.Object{}, errors.New("bucket not found")
+}
+
+func (failingSink) Location() string {
+	return "failing"
+}
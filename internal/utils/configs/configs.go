@@ -46,11 +46,24 @@ const (
 	HaulerConsumerKafkaTopic = "hauler_consumer_kafka_topic_name"
 	HaulerProducerKafkaTopic = "hauler_producer_kafka_topic_name"
 	HaulerHarmonyKafkaTopic  = "hauler_harmony_kafka_topic_name"
+	HaulerDeadLetterTopic    = "hauler_dead_letter_kafka_topic_name"
+	HaulerRetryKafkaTopic    = "hauler_retry_kafka_topic_name"
 	kafkaBootstrapServers    = "kafka_bootstrap_servers"
 	kafkaDualStack           = "kafka_dual_stack"
 	kafkaBatchSize           = "kafka_batch_size"
 	kafkaTLSOn               = "kafka_tls_mode"
+	kafkaTLSCAFile           = "kafka_tls_ca_file"
+	kafkaTLSCertFile         = "kafka_tls_cert_file"
+	kafkaTLSKeyFile          = "kafka_tls_key_file"
+	kafkaSASLMechanism       = "kafka_sasl_mechanism"
 	kafkaTimeoutInSeconds    = "kafka_timeout_in_seconds"
+	harmonyMaxAttempts       = "harmony_max_attempts"
+	kafkaCommitInterval      = "kafka_commit_interval_seconds"
+	kafkaConsumerWorkers     = "kafka_consumer_workers"
+	kafkaSerializeCustomers  = "kafka_serialize_customers"
+	collectionMaxAttempts    = "collection_max_attempts"
+	collectionRetryDelay     = "collection_retry_delay_seconds"
+	collectionPendingRetries = "collection_pending_retries"
 
 	kafkaEventSource = "kafka_event_source"
 	kafkaEventType   = "kafka_event_type"
@@ -74,6 +87,40 @@ const (
 	// Rest
 	apiURL                = "api_url"
 	restConnectionTimeout = "rest_connection_timeout"
+	restMaxAttempts       = "rest_max_attempts"
+	restMaxElapsedTime    = "rest_max_elapsed_time"
+	// SSO client credentials
+	tokenURL           = "token_url"
+	tokenRefreshBefore = "token_refresh_before"
+	credentialsDir     = "credentials_dir"
+	credentialsFile    = "credentials_file"
+	// Secrets
+	secretsProvider = "secrets_provider"
+	secretsDir      = "secrets_dir"
+	secretsFile     = "secrets_file"
+	secretsKey      = "secrets_key"
+	// Rate limits, in requests per second per customer
+	rateLimitAPI              = "rate_limit_api"
+	rateLimitBackupRecovery   = "rate_limit_backup_recovery"
+	rateLimitDisasterRecovery = "rate_limit_disaster_recovery"
+	rateLimitBurst            = "rate_limit_burst"
+	// Circuit breaker
+	circuitBreakerFailures = "circuit_breaker_failures"
+	circuitBreakerCooldown = "circuit_breaker_cooldown"
+	// Collection
+	collectorConcurrency = "collector_concurrency"
+	fanOutConcurrency    = "fanout_concurrency"
+	fanOutRequestTimeout = "fanout_request_timeout"
+	// Sink
+	sinkType      = "sink_type"
+	sinkEndpoint  = "sink_endpoint"
+	sinkPathStyle = "sink_path_style"
+	sinkLocalDir  = "sink_local_dir"
+	compression   = "compression"
+	outputFormat  = "output_format"
+	// Deduplication
+	dedupTTL  = "dedup_ttl_seconds"
+	dedupFile = "dedup_file"
 )
 
 //nolint:gochecknoinits // This can be ignored
@@ -118,6 +165,8 @@ func init() {
 	viper.SetDefault(HaulerConsumerKafkaTopic, constants.HaulerConsumerKafkaTopic)
 	viper.SetDefault(HaulerProducerKafkaTopic, constants.HaulerProducerKafkaTopic)
 	viper.SetDefault(HaulerHarmonyKafkaTopic, constants.HaulerHarmonyKafkaTopic)
+	viper.SetDefault(HaulerDeadLetterTopic, constants.HaulerDeadLetterTopic)
+	viper.SetDefault(HaulerRetryKafkaTopic, constants.HaulerRetryKafkaTopic)
 	viper.SetDefault(HaulerKafkaGroupID, constants.HaulerConsumerGroupID)
 
 	viper.SetDefault(kafkaBootstrapServers, constants.KafkaBootstrapServers)
@@ -125,8 +174,19 @@ func init() {
 	viper.SetDefault(kafkaDualStack, true)
 	viper.SetDefault(kafkaBatchSize, 1)                // we need a pretty quick response back to CCS, so don't wait for a batch to fill up
 	viper.SetDefault(kafkaTLSOn, constants.KafkaTLSOn) // TLS encryption is on for AWS and off for ccs-dev environment
+	viper.SetDefault(kafkaTLSCAFile, "")
+	viper.SetDefault(kafkaTLSCertFile, "")
+	viper.SetDefault(kafkaTLSKeyFile, "")
+	viper.SetDefault(kafkaSASLMechanism, "")
 	viper.SetDefault(kafkaEventSource, constants.KafkaEventSource)
 	viper.SetDefault(kafkaEventType, constants.KafkaEventType)
+	viper.SetDefault(harmonyMaxAttempts, constants.HarmonyMaxAttempts)
+	viper.SetDefault(kafkaCommitInterval, constants.KafkaCommitIntervalSecs)
+	viper.SetDefault(kafkaConsumerWorkers, constants.KafkaConsumerWorkers)
+	viper.SetDefault(collectionMaxAttempts, constants.CollectionMaxAttempts)
+	viper.SetDefault(collectionRetryDelay, constants.CollectionRetryDelaySecs)
+	viper.SetDefault(collectionPendingRetries, constants.CollectionPendingRetries)
+	viper.SetDefault(kafkaSerializeCustomers, true)
 	viper.SetDefault(internalJwt, constants.InternalJwt)
 	viper.SetDefault(AWSAccessKey, constants.AWSAccessKeyID)
 	viper.SetDefault(AWSRegion, constants.AWSRegion)
@@ -138,6 +198,36 @@ func init() {
 	// Rest
 	viper.SetDefault(apiURL, constants.APIURL)
 	viper.SetDefault(restConnectionTimeout, constants.RestTimeoutSecs)
+	viper.SetDefault(restMaxAttempts, constants.RestMaxAttempts)
+	viper.SetDefault(restMaxElapsedTime, constants.RestMaxElapsedTimeSecs)
+	viper.SetDefault(tokenURL, constants.TokenURL)
+	viper.SetDefault(tokenRefreshBefore, constants.TokenRefreshBeforeSecs)
+	viper.SetDefault(credentialsDir, "")
+	viper.SetDefault(credentialsFile, "")
+	viper.SetDefault(secretsProvider, constants.SecretsProvider)
+	viper.SetDefault(secretsDir, constants.SecretsDir)
+	viper.SetDefault(secretsFile, "")
+	viper.SetDefault(secretsKey, "")
+	viper.SetDefault(rateLimitAPI, constants.RateLimitAPI)
+	viper.SetDefault(rateLimitBackupRecovery, constants.RateLimitBackupRecovery)
+	viper.SetDefault(rateLimitDisasterRecovery, constants.RateLimitDisasterRecovery)
+	viper.SetDefault(rateLimitBurst, constants.RateLimitBurst)
+	viper.SetDefault(circuitBreakerFailures, constants.CircuitBreakerFailures)
+	viper.SetDefault(circuitBreakerCooldown, constants.CircuitBreakerCooldownSecs)
+	// Collection
+	viper.SetDefault(collectorConcurrency, constants.CollectorConcurrency)
+	viper.SetDefault(fanOutConcurrency, constants.FanOutConcurrency)
+	viper.SetDefault(fanOutRequestTimeout, constants.FanOutRequestTimeoutSecs)
+	// Sink
+	viper.SetDefault(sinkType, constants.SinkType)
+	viper.SetDefault(sinkEndpoint, "")
+	viper.SetDefault(sinkPathStyle, false)
+	viper.SetDefault(sinkLocalDir, constants.SinkLocalDir)
+	viper.SetDefault(compression, constants.Compression)
+	viper.SetDefault(outputFormat, constants.OutputFormat)
+	// Deduplication
+	viper.SetDefault(dedupTTL, constants.DedupTTLSecs)
+	viper.SetDefault(dedupFile, "")
 }
 
 // GetHTTPPort returns port to listen on for HTTP requests
@@ -236,6 +326,26 @@ func GetKafkaEventType() string {
 	return viper.GetString(kafkaEventType)
 }
 
+// GetKafkaCommitInterval returns how often the offsets of the processed messages are committed
+func GetKafkaCommitInterval() time.Duration {
+	return time.Duration(viper.GetInt(kafkaCommitInterval)) * time.Second
+}
+
+// GetKafkaConsumerWorkers returns the number of collection requests processed at once
+func GetKafkaConsumerWorkers() int {
+	return viper.GetInt(kafkaConsumerWorkers)
+}
+
+// GetKafkaSerializeCustomers tells whether the requests of a customer are processed one at a time
+func GetKafkaSerializeCustomers() bool {
+	return viper.GetBool(kafkaSerializeCustomers)
+}
+
+// GetHarmonyMaxAttempts returns the number of attempts to publish a file notification, including the first one
+func GetHarmonyMaxAttempts() int {
+	return viper.GetInt(harmonyMaxAttempts)
+}
+
 // GetHaulerKafkaGroupID returns consumer group id
 func GetHaulerKafkaGroupID() string {
 	return viper.GetString(HaulerKafkaGroupID)
@@ -256,6 +366,33 @@ func GetHaulerHarmonyKafkaTopic() string {
 	return viper.GetString(HaulerHarmonyKafkaTopic)
 }
 
+// GetHaulerRetryTopic returns the topic the failed collections are re-enqueued to, retrying is disabled when empty
+func GetHaulerRetryTopic() string {
+	return viper.GetString(HaulerRetryKafkaTopic)
+}
+
+// GetCollectionMaxAttempts returns the number of attempts of a collection, including the first one
+func GetCollectionMaxAttempts() int {
+	return viper.GetInt(collectionMaxAttempts)
+}
+
+// GetCollectionRetryDelay returns the delay before the first retry of a collection, doubling on every retry
+func GetCollectionRetryDelay() time.Duration {
+	return time.Duration(viper.GetInt(collectionRetryDelay)) * time.Second
+}
+
+// GetCollectionPendingRetries returns the number of retried collections waiting for their delay at once,
+// fetching pauses beyond
+func GetCollectionPendingRetries() int {
+	return viper.GetInt(collectionPendingRetries)
+}
+
+// GetHaulerDeadLetterTopic returns the topic the unprocessable collection requests are forwarded to,
+// empty when they are dropped
+func GetHaulerDeadLetterTopic() string {
+	return viper.GetString(HaulerDeadLetterTopic)
+}
+
 // GetKafkaBootstrapServers returns a comma separated list of Kafka bootstrap servers
 func GetKafkaBootstrapServers() string {
 	return viper.GetString(kafkaBootstrapServers)
@@ -280,6 +417,26 @@ func GetKafkaTLSOn() bool {
 	return viper.GetBool(kafkaTLSOn)
 }
 
+// GetKafkaTLSCAFile returns the PEM bundle of the CAs the brokers are verified against, the system ones when empty
+func GetKafkaTLSCAFile() string {
+	return viper.GetString(kafkaTLSCAFile)
+}
+
+// GetKafkaTLSCertFile returns the PEM client certificate presented to the brokers for mutual TLS, if any
+func GetKafkaTLSCertFile() string {
+	return viper.GetString(kafkaTLSCertFile)
+}
+
+// GetKafkaTLSKeyFile returns the PEM private key of the client certificate
+func GetKafkaTLSKeyFile() string {
+	return viper.GetString(kafkaTLSKeyFile)
+}
+
+// GetKafkaSASLMechanism returns the SASL mechanism, PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, SASL is off when empty
+func GetKafkaSASLMechanism() string {
+	return viper.GetString(kafkaSASLMechanism)
+}
+
 func GetInternalJwt() string {
 	return viper.GetString(internalJwt)
 }
@@ -329,6 +486,86 @@ func GetRestConnectionTimeout() time.Duration {
 	return time.Duration(viper.GetInt(restConnectionTimeout)) * time.Second
 }
 
+// GetRestMaxAttempts returns the number of attempts of an idempotent REST call, including the first one
+func GetRestMaxAttempts() int {
+	return viper.GetInt(restMaxAttempts)
+}
+
+// GetRestMaxElapsedTime returns the maximum time spent retrying a REST call
+func GetRestMaxElapsedTime() time.Duration {
+	return time.Duration(viper.GetInt(restMaxElapsedTime)) * time.Second
+}
+
+// GetTokenURL returns the SSO endpoint issuing the API access tokens
+func GetTokenURL() string {
+	return viper.GetString(tokenURL)
+}
+
+// GetTokenRefreshBefore returns how long before its expiry an access token is refreshed
+func GetTokenRefreshBefore() time.Duration {
+	return time.Duration(viper.GetInt(tokenRefreshBefore)) * time.Second
+}
+
+// GetCredentialsDir returns the mounted secrets directory holding the per customer client credentials, if any
+func GetCredentialsDir() string {
+	return viper.GetString(credentialsDir)
+}
+
+// GetCredentialsFile returns the JSON file holding the per customer client credentials, if any
+func GetCredentialsFile() string {
+	return viper.GetString(credentialsFile)
+}
+
+// GetSecretsProvider returns where the secrets are read from: env, file or encrypted-file
+func GetSecretsProvider() string {
+	return viper.GetString(secretsProvider)
+}
+
+// GetSecretsDir returns the directory of the secret files mounted from Kubernetes secrets
+func GetSecretsDir() string {
+	return viper.GetString(secretsDir)
+}
+
+// GetSecretsFile returns the path of the local encrypted secrets file
+func GetSecretsFile() string {
+	return viper.GetString(secretsFile)
+}
+
+// GetSecretsKey returns the hex encoded AES key of the local encrypted secrets file
+func GetSecretsKey() string {
+	return viper.GetString(secretsKey)
+}
+
+// GetRateLimitAPI returns the requests per second allowed per customer on /api/v1, 0 disables the limit
+func GetRateLimitAPI() float64 {
+	return viper.GetFloat64(rateLimitAPI)
+}
+
+// GetRateLimitBackupRecovery returns the requests per second allowed per customer on /backup-recovery/v1beta1
+func GetRateLimitBackupRecovery() float64 {
+	return viper.GetFloat64(rateLimitBackupRecovery)
+}
+
+// GetRateLimitDisasterRecovery returns the requests per second allowed per customer on /disaster-recovery/v1beta1
+func GetRateLimitDisasterRecovery() float64 {
+	return viper.GetFloat64(rateLimitDisasterRecovery)
+}
+
+// GetRateLimitBurst returns the number of requests a customer may send at once on a rate limited API
+func GetRateLimitBurst() int {
+	return viper.GetInt(rateLimitBurst)
+}
+
+// GetCircuitBreakerFailures returns the consecutive failures opening the circuit of an endpoint, 0 disables it
+func GetCircuitBreakerFailures() int {
+	return viper.GetInt(circuitBreakerFailures)
+}
+
+// GetCircuitBreakerCooldown returns how long an open circuit rejects requests before letting a probe through
+func GetCircuitBreakerCooldown() time.Duration {
+	return time.Duration(viper.GetInt(circuitBreakerCooldown)) * time.Second
+}
+
 func GetLocalCluster() bool {
 	return viper.GetBool(localCluster)
 }
@@ -340,3 +577,58 @@ func SetLocalCluster(flag bool) {
 func GetAPIURL() string {
 	return viper.GetString(apiURL)
 }
+
+// GetCollectorConcurrency returns how many asset types are collected in parallel for a customer
+func GetCollectorConcurrency() int {
+	return viper.GetInt(collectorConcurrency)
+}
+
+// GetFanOutConcurrency returns how many per asset backup/snapshot requests run in parallel for a collection
+func GetFanOutConcurrency() int {
+	return viper.GetInt(fanOutConcurrency)
+}
+
+// GetFanOutRequestTimeout returns the deadline of the paginated requests issued for a single asset
+func GetFanOutRequestTimeout() time.Duration {
+	return time.Duration(viper.GetInt(fanOutRequestTimeout)) * time.Second
+}
+
+// GetSinkType returns where the collected files are stored: s3, s3-compatible or local
+func GetSinkType() string {
+	return viper.GetString(sinkType)
+}
+
+// GetSinkEndpoint returns the URL of the S3-compatible service, such as MinIO
+func GetSinkEndpoint() string {
+	return viper.GetString(sinkEndpoint)
+}
+
+// GetSinkPathStyle returns whether the bucket is addressed in the URL path rather than in the host name
+func GetSinkPathStyle() bool {
+	return viper.GetBool(sinkPathStyle)
+}
+
+// GetCompression returns the codec applied to the stored files: none, gzip or zstd
+func GetCompression() string {
+	return viper.GetString(compression)
+}
+
+// GetOutputFormat returns the default format of the asset files, json, ndjson or parquet
+func GetOutputFormat() string {
+	return viper.GetString(outputFormat)
+}
+
+// GetSinkLocalDir returns the directory the collected files are written to by the local sink
+func GetSinkLocalDir() string {
+	return viper.GetString(sinkLocalDir)
+}
+
+// GetDedupTTL returns how long the result of a collection is remembered to answer duplicate requests
+func GetDedupTTL() time.Duration {
+	return time.Duration(viper.GetInt(dedupTTL)) * time.Second
+}
+
+// GetDedupFile returns the file the collection results are persisted to across restarts, if any
+func GetDedupFile() string {
+	return viper.GetString(dedupFile)
+}
@@ -49,6 +49,10 @@ func TestConfiguration(t *testing.T) {
 	assert.Empty(t, GetInternalJwt())
 	assert.NotZero(t, GetUpdateAuthzServiceTokenTimeout())
 	GetKafkaTLSOn()
+	assert.Empty(t, GetKafkaTLSCAFile())
+	assert.Empty(t, GetKafkaTLSCertFile())
+	assert.Empty(t, GetKafkaTLSKeyFile())
+	assert.Empty(t, GetKafkaSASLMechanism())
 	GetKafkaTimeoutInSeconds()
 	GetKafkaBatchSize()
 	GetKafkaDualStackOn()
@@ -64,6 +68,42 @@ func TestConfiguration(t *testing.T) {
 	GetAPIURL()
 	SetFleetGrpcEndpoint(fleetEndPoint)
 	GetRestConnectionTimeout()
+	GetRestMaxAttempts()
+	GetRestMaxElapsedTime()
+	assert.NotEmpty(t, GetTokenURL())
+	GetCredentialsDir()
+	GetCredentialsFile()
+	assert.NotEmpty(t, GetSecretsProvider())
+	assert.NotEmpty(t, GetSecretsDir())
+	GetSecretsFile()
+	GetSecretsKey()
+	assert.NotZero(t, GetTokenRefreshBefore())
+	assert.NotZero(t, GetRateLimitAPI())
+	assert.NotZero(t, GetRateLimitBackupRecovery())
+	assert.NotZero(t, GetRateLimitDisasterRecovery())
+	assert.NotZero(t, GetRateLimitBurst())
+	assert.NotEmpty(t, GetSinkType())
+	assert.NotEmpty(t, GetSinkLocalDir())
+	assert.NotEmpty(t, GetCompression())
+	assert.NotEmpty(t, GetOutputFormat())
+	GetSinkEndpoint()
+	GetSinkPathStyle()
+	assert.NotZero(t, GetDedupTTL())
+	assert.Empty(t, GetDedupFile())
+	assert.NotZero(t, GetCircuitBreakerFailures())
+	assert.NotZero(t, GetCircuitBreakerCooldown())
+	assert.NotZero(t, GetCollectorConcurrency())
+	assert.NotZero(t, GetFanOutConcurrency())
+	assert.NotZero(t, GetFanOutRequestTimeout())
+	assert.NotZero(t, GetHarmonyMaxAttempts())
+	assert.NotZero(t, GetKafkaCommitInterval())
+	assert.NotZero(t, GetKafkaConsumerWorkers())
+	assert.True(t, GetKafkaSerializeCustomers())
+	assert.NotEmpty(t, GetHaulerDeadLetterTopic())
+	assert.NotEmpty(t, GetHaulerRetryTopic())
+	assert.NotZero(t, GetCollectionMaxAttempts())
+	assert.NotZero(t, GetCollectionRetryDelay())
+	assert.NotZero(t, GetCollectionPendingRetries())
 	assert.Equal(t, GetFleetGrpcEndpoint(), fleetEndPoint)
 	SetFleetGrpcDeviceType1Endpoint(deviceType1EndPoint)
 	assert.Equal(t, GetFleetGrpcDeviceType1Endpoint(), deviceType1EndPoint)
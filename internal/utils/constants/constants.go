@@ -54,11 +54,21 @@ const (
 	HaulerConsumerKafkaTopic = "panorama.common.hauler.collection"
 	HaulerProducerKafkaTopic = "panorama.hauler.collection.status"
 	HaulerHarmonyKafkaTopic  = "panorama.common.hdpfilesync"
+	HaulerDeadLetterTopic    = "panorama.common.hauler.collection.dlq"
+	HaulerRetryKafkaTopic    = "panorama.common.hauler.collection.retry"
 	HaulerConsumerGroupID    = "panorama.common.hauler.consumergroup"
 
 	KafkaEventSource = "https://github.hpe.com/nimble-dcs/panorama-common-hauler"
 	KafkaEventType   = "fleet.common.hauler.collection"
 
+	HarmonyMaxAttempts      = 3
+	KafkaCommitIntervalSecs = 5
+	KafkaConsumerWorkers    = 8
+
+	CollectionMaxAttempts    = 3
+	CollectionRetryDelaySecs = 60
+	CollectionPendingRetries = 100
+
 	// JSON Version
 	Version = "1.0"
 
@@ -79,7 +89,35 @@ const (
 	FileDomain = "panorama"
 
 	// Rest
-	RestTimeoutSecs = 60
+	RestTimeoutSecs        = 60
+	RestMaxAttempts        = 4
+	RestMaxElapsedTimeSecs = 120
+	// SSO
+	TokenURL               = "https://sso.common.cloud.hpe.com/as/token.oauth2"
+	TokenRefreshBeforeSecs = 60
+	// Secrets
+	SecretsProvider = "env"
+	SecretsDir      = "/etc/secrets"
+	// Rate limits, in requests per second per customer
+	RateLimitAPI              = 20
+	RateLimitBackupRecovery   = 10
+	RateLimitDisasterRecovery = 10
+	RateLimitBurst            = 10
+	// Circuit breaker
+	CircuitBreakerFailures     = 5
+	CircuitBreakerCooldownSecs = 30
+	// Sink
+	SinkType     = "s3"
+	SinkLocalDir = "/tmp/hauler"
+	Compression  = "none"
+	OutputFormat = "json"
+	// Deduplication
+	DedupTTLSecs = 24 * 60 * 60
 	// APIURL          = "https://hcipoc-app.qa.cds.hpe.com"
 	APIURL = "https://scdev01-app.qa.cds.hpe.com"
+
+	// Collection
+	CollectorConcurrency     = 4
+	FanOutConcurrency        = 8
+	FanOutRequestTimeoutSecs = 120
 )
@@ -15,6 +15,7 @@ const (
 	BodyNotFoundErrorMsg         = "Request body not found"
 	InvalidPathParamErrorMsg     = "Path parameter is invalid"
 	InvalidRequestFormatErrorMsg = "Request format is invalid"
+	CircuitOpenErrorMsg          = "Circuit breaker is open"
 )
 
 // InternalError: All kinds of connection errors (to DB or kafka), marshaling errors
@@ -142,10 +143,30 @@ func (e InvalidRequestFormatError) Error() string {
 type RestClientError struct {
 	StatusCode int
 	Err        error
+	// Endpoint is the method and request URI of the failed call
+	Endpoint string
+	// Body is an excerpt of the response body returned by the API
+	Body string
+	// RequestID is the x-request-id of the failed call, if any
+	RequestID string
 }
 
 func (e RestClientError) Error() string {
-	return e.Err.Error()
+	if e.Endpoint == "" {
+		return e.Err.Error()
+	}
+	msg := fmt.Sprintf("%s: %s returned %d", e.Err.Error(), e.Endpoint, e.StatusCode)
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	if e.Body != "" {
+		msg += fmt.Sprintf(": %s", e.Body)
+	}
+	return msg
+}
+
+func (e RestClientError) Unwrap() error {
+	return e.Err
 }
 
 func (e RestClientError) Is(target error) bool {
@@ -154,3 +175,20 @@ func (e RestClientError) Is(target error) bool {
 	}
 	return false
 }
+
+// Circuit open error, returned without calling an endpoint that keeps failing
+
+type CircuitOpenError struct {
+	Endpoint string
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s: %s", CircuitOpenErrorMsg, e.Endpoint)
+}
+
+func (e CircuitOpenError) Is(target error) bool {
+	if _, ok := target.(CircuitOpenError); ok {
+		return true
+	}
+	return false
+}
@@ -70,6 +70,12 @@ func TestRestClientError_Error(t *testing.T) {
 	assert.Equal(t, err.Error(), "Failed")
 }
 
+func TestRestClientError_ErrorWithEndpoint(t *testing.T) {
+	err := RestClientError{StatusCode: 404, Err: errors.New("Not Found"), Endpoint: "GET /api/v1/datastores",
+		Body: `{"message":"not found"}`, RequestID: "abc"}
+	assert.Equal(t, err.Error(), `Not Found: GET /api/v1/datastores returned 404 (request id abc): {"message":"not found"}`)
+}
+
 func TestRestClientError_Is(t *testing.T) {
 	error1 := RestClientError{}
 	error2 := RestClientError{Err: errors.New("Failed")}
@@ -115,3 +121,9 @@ func TestInvalidInputArgError_Error(t *testing.T) {
 	err := InvalidInputArgError{Err: errors.New("Failed")}
 	assert.Equal(t, err.Error(), "Invalid input argument Error: Failed")
 }
+
+func TestCircuitOpenError_Error(t *testing.T) {
+	err := CircuitOpenError{Endpoint: "GET /api/v1/datastores"}
+	assert.Equal(t, err.Error(), "Circuit breaker is open: GET /api/v1/datastores")
+	assert.True(t, errors.Is(err, CircuitOpenError{}))
+}
@@ -0,0 +1,138 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+// Package auth builds the TLS and SASL settings shared by the Kafka readers, writers and dialers.
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/secrets"
+)
+
+// SASL mechanisms, see configs.GetKafkaSASLMechanism
+const (
+	Plain       = "PLAIN"
+	ScramSHA256 = "SCRAM-SHA-256"
+	ScramSHA512 = "SCRAM-SHA-512"
+)
+
+var (
+	errUnknownMechanism   = errors.New("unknown SASL mechanism")
+	errMissingCredentials = errors.New("SASL username and password are not set")
+	errEmptyCABundle      = errors.New("no certificate found in CA bundle")
+	errIncompleteKeyPair  = errors.New("client certificate and key must be set together")
+)
+
+// Settings tell how the Kafka clients authenticate to the brokers.
+type Settings struct {
+	// TLS is nil when TLS is off
+	TLS *tls.Config
+	// SASL is nil when SASL is off
+	SASL sasl.Mechanism
+}
+
+// SettingsFromConfig returns the configured settings. TLS is on with the Kafka TLS mode, verifying
+// the brokers against the CA bundle, or the system roots when unset, and presenting the client
+// certificate, if any, for mutual TLS. SASL credentials are read from the secrets provider.
+func SettingsFromConfig(ctx context.Context) (Settings, error) {
+	var settings Settings
+	var err error
+	if configs.GetKafkaTLSOn() {
+		settings.TLS, err = TLSConfig(configs.GetKafkaTLSCAFile(), configs.GetKafkaTLSCertFile(),
+			configs.GetKafkaTLSKeyFile())
+		if err != nil {
+			return Settings{}, err
+		}
+	}
+	if mechanism := configs.GetKafkaSASLMechanism(); mechanism != "" {
+		settings.SASL, err = Mechanism(mechanism, secrets.GetOrEmpty(ctx, secrets.KafkaUsername),
+			secrets.GetOrEmpty(ctx, secrets.KafkaPassword))
+		if err != nil {
+			return Settings{}, err
+		}
+	}
+	return settings, nil
+}
+
+// TLSConfig returns the TLS configuration trusting the certificates of caFile, all of them optional.
+// certFile and keyFile hold the client certificate presented to the brokers.
+func TLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		bundle, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("%w: %s", errEmptyCABundle, caFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errIncompleteKeyPair
+	}
+	if certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// Mechanism returns the SASL mechanism of the given name, case insensitive.
+func Mechanism(name, username, password string) (sasl.Mechanism, error) {
+	if username == "" || password == "" {
+		return nil, errMissingCredentials
+	}
+	switch strings.ToUpper(name) {
+	case Plain:
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case ScramSHA256:
+		return scram.Mechanism(scram.SHA256, username, password)
+	case ScramSHA512:
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownMechanism, name)
+	}
+}
+
+// Dialer returns the dialer of the readers and health checks.
+func (s Settings) Dialer(timeout time.Duration, dualStack bool) *kafka.Dialer {
+	return &kafka.Dialer{
+		Timeout:       timeout,
+		DualStack:     dualStack,
+		TLS:           s.TLS,
+		SASLMechanism: s.SASL,
+	}
+}
+
+// Transport returns the transport of the writers, nil for the default plaintext one.
+func (s Settings) Transport() kafka.RoundTripper {
+	if s.TLS == nil && s.SASL == nil {
+		return nil
+	}
+	return &kafka.Transport{TLS: s.TLS, SASL: s.SASL}
+}
+
+// MechanismName returns the name of the SASL mechanism, empty when SASL is off.
+func (s Settings) MechanismName() string {
+	if s.SASL == nil {
+		return ""
+	}
+	return s.SASL.Name()
+}
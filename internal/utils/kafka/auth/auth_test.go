@@ -0,0 +1,100 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate and its key to dir, returning their paths.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "hauler"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		0o600))
+	return certFile, keyFile
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+
+	config, err := TLSConfig("", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, config.RootCAs, "the system roots are used")
+	assert.Empty(t, config.Certificates)
+
+	config, err = TLSConfig(certFile, certFile, keyFile)
+	require.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+
+	_, err = TLSConfig("", certFile, "")
+	assert.ErrorIs(t, err, errIncompleteKeyPair)
+	_, err = TLSConfig(keyFile, "", "")
+	assert.ErrorIs(t, err, errEmptyCABundle)
+	_, err = TLSConfig(filepath.Join(dir, "missing.pem"), "", "")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestMechanism(t *testing.T) {
+	for _, name := range []string{Plain, ScramSHA256, ScramSHA512, "scram-sha-512"} {
+		mechanism, err := Mechanism(name, "user", "password")
+		require.NoError(t, err, name)
+		assert.Equal(t, Settings{SASL: mechanism}.MechanismName(), mechanism.Name())
+	}
+	_, err := Mechanism("GSSAPI", "user", "password")
+	assert.ErrorIs(t, err, errUnknownMechanism)
+	_, err = Mechanism(Plain, "user", "")
+	assert.ErrorIs(t, err, errMissingCredentials)
+}
+
+func TestSettingsClients(t *testing.T) {
+	assert.Nil(t, Settings{}.Transport(), "plaintext writers use the default transport")
+	assert.Empty(t, Settings{}.MechanismName())
+
+	config, err := TLSConfig("", "", "")
+	require.NoError(t, err)
+	mechanism, err := Mechanism(ScramSHA256, "user", "password")
+	require.NoError(t, err)
+	settings := Settings{TLS: config, SASL: mechanism}
+
+	transport, ok := settings.Transport().(*kafka.Transport)
+	require.True(t, ok)
+	assert.Same(t, config, transport.TLS)
+	assert.Equal(t, mechanism, transport.SASL)
+
+	dialer := settings.Dialer(5*time.Second, true)
+	assert.Same(t, config, dialer.TLS)
+	assert.Equal(t, mechanism, dialer.SASLMechanism)
+	assert.Equal(t, 5*time.Second, dialer.Timeout)
+}
@@ -4,12 +4,13 @@ package consumer
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"strings"
-	"sync"
 	"time"
 
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/auth"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/logging"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/tracer"
@@ -21,16 +22,24 @@ import (
 
 var (
 	logger                       = logging.GetLogger()
-	wg                           sync.WaitGroup
-	msgCount                     = 0
-	msgMap                       = make(map[int]kafka.Message)
 	schedulerMessageToDispatcher = Dispatcher(dispatchMessage)
 )
 
 const (
-	MinimumCommitCount = 5
+	// DeadLetterReasonHeader holds the DeadLetterReason of a message forwarded to the dead-letter topic
+	DeadLetterReasonHeader = "dlq_reason"
 )
 
+// DeadLetterReason tells why a message was forwarded to the dead-letter topic and where it came from.
+type DeadLetterReason struct {
+	Reason    string    `json:"reason"`
+	Error     string    `json:"error"`
+	Topic     string    `json:"topic"`
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Time      time.Time `json:"time"`
+}
+
 type Consumer interface {
 	ReadAndProcessMessages(ctx context.Context, done chan bool)
 }
@@ -38,34 +47,51 @@ type Consumer interface {
 type Impl struct {
 	topic             string
 	groupID           string
-	sslMode           bool
+	security          auth.Settings
 	brokers           string
 	client            ReaderClient
 	schedulerProducer producer.Producer
 	harmonyProducer   producer.Producer
+	deadLetter        producer.Forwarder
+	retryForwarder    producer.Forwarder
+	retryPolicy       CollectionRetryPolicy
+	pool              *WorkerPool
+	// pending bounds the messages waiting for their retry delay
+	pending chan struct{}
 }
 
-// NewConsumer creates a new Kafka consumer for a specified topic
+// NewConsumer creates a new Kafka consumer for a specified topic, processing its messages on the
+// workers of pool, which may be shared with other consumers. Rejected messages are forwarded with
+// deadLetter, or dropped when it is nil. Failed collections are re-enqueued with retry, or only
+// logged when it is nil.
 func NewConsumer(
 	topic string,
 	groupID string,
-	sslMode bool,
+	security auth.Settings,
 	brokers string,
 	schedulerProducer producer.Producer,
 	harmonyProducer producer.Producer,
+	deadLetter producer.Forwarder,
+	retry producer.Forwarder,
+	pool *WorkerPool,
 ) Consumer {
 	return &Impl{
 		topic:             topic,
 		groupID:           groupID,
-		sslMode:           sslMode,
+		security:          security,
 		brokers:           brokers,
 		client:            &ReaderClientImpl{},
 		schedulerProducer: schedulerProducer,
 		harmonyProducer:   harmonyProducer,
+		deadLetter:        deadLetter,
+		retryForwarder:    retry,
+		retryPolicy:       CollectionRetryPolicyFromConfig(),
+		pool:              pool,
+		pending:           newPendingRetries(configs.GetCollectionPendingRetries()),
 	}
 }
 
-func buildReaderConfig(topic, groupID string, sslMode bool, brokers string) *kafka.ReaderConfig {
+func buildReaderConfig(topic, groupID string, security auth.Settings, brokers string) *kafka.ReaderConfig {
 	cfg := &kafka.ReaderConfig{
 		Brokers:     strings.Split(brokers, ","),
 		Topic:       topic,
@@ -74,37 +100,79 @@ func buildReaderConfig(topic, groupID string, sslMode bool, brokers string) *kaf
 		MinBytes:    1,    // Get message as soon as it is available
 		MaxBytes:    1000, // Batch size
 	}
-	if sslMode {
-		cfg.Dialer = &kafka.Dialer{
-			TLS:       &tls.Config{MinVersion: tls.VersionTLS12},
-			Timeout:   time.Duration(configs.GetKafkaTimeoutInSeconds()) * time.Second,
-			DualStack: configs.GetKafkaDualStackOn(),
-		}
+	if security.TLS != nil || security.SASL != nil {
+		cfg.Dialer = security.Dialer(time.Duration(configs.GetKafkaTimeoutInSeconds())*time.Second,
+			configs.GetKafkaDualStackOn())
 	}
 	return cfg
 }
 
 func (c *Impl) ReadAndProcessMessages(
 	ctx context.Context, done chan bool) {
-	configuration := buildReaderConfig(c.topic, c.groupID, c.sslMode, c.brokers)
+	configuration := buildReaderConfig(c.topic, c.groupID, c.security, c.brokers)
 	logger.WithContext(ctx).Info("Reading from kafka",
 		zap.String("groupID", c.groupID),
-		zap.Bool("sslMode", c.sslMode),
+		zap.Bool("sslMode", c.security.TLS != nil),
+		zap.String("sasl", c.security.MechanismName()),
 		zap.String("brokers", c.brokers),
 	)
 	reader := c.client.NewReader(configuration)
 	logger.WithContext(ctx).Info("Kafka message reader is created")
 	defer c.client.Close(reader)
 
+	tracker := NewOffsetTracker(c.topic)
+	committerDone := make(chan struct{})
+	go func() {
+		defer close(committerDone)
+		c.commitPeriodically(ctx, reader, tracker, configs.GetKafkaCommitInterval())
+	}()
+
 	for ctx.Err() == nil {
-		c.readAndDispatchMessage(ctx, reader, c.schedulerProducer, c.harmonyProducer)
+		c.readAndDispatchMessage(ctx, reader, tracker, c.schedulerProducer, c.harmonyProducer)
 	}
 	logger.WithContext(ctx).Info("Kafka message reader is exiting")
+	// commit what was processed before the shutdown, interrupted messages are redelivered
+	tracker.Wait()
+	<-committerDone
+	c.commit(context.Background(), reader, tracker)
 	done <- true
 }
 
+// commitPeriodically commits the offsets of the processed messages every interval until ctx is done.
+func (c *Impl) commitPeriodically(ctx context.Context, reader *kafka.Reader, tracker *OffsetTracker,
+	interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.commit(ctx, reader, tracker)
+		}
+	}
+}
+
+// commit commits the highest contiguous processed offset of every partition.
+func (c *Impl) commit(ctx context.Context, reader *kafka.Reader, tracker *OffsetTracker) {
+	for _, msg := range tracker.Ready() {
+		commitMsg := msg
+		if err := c.client.CommitMessage(ctx, reader, &commitMsg); err != nil {
+			logger.WithContext(ctx).Error("Failed to commit message", zap.Int("partition", commitMsg.Partition),
+				zap.Int64("offset", commitMsg.Offset), zap.Error(err))
+			continue
+		}
+		tracker.Committed(commitMsg)
+		logger.WithContext(ctx).Info("Committed message",
+			zap.Int("partition", commitMsg.Partition),
+			zap.Int64("offset", commitMsg.Offset),
+			zap.String("key", string(commitMsg.Key)),
+		)
+	}
+}
+
 func (c *Impl) readAndDispatchMessage(ctx context.Context,
-	reader *kafka.Reader, schedulerProducer producer.Producer,
+	reader *kafka.Reader, tracker *OffsetTracker, schedulerProducer producer.Producer,
 	harmonyProducer producer.Producer) {
 	msg, err := c.client.FetchMessage(ctx, reader)
 	if err != nil {
@@ -131,31 +199,108 @@ func (c *Impl) readAndDispatchMessage(ctx context.Context,
 		zap.String("value", string(msg.Value)))
 
 	// dispatch message here to hauler for collection
-	dispatcher := schedulerMessageToDispatcher
-	wg.Add(1)
-	go dispatcher(ctx, msg, schedulerProducer, harmonyProducer)
-
-	msgMap[msg.Partition] = msg
-	msgCount++
-
-	if msgCount%MinimumCommitCount == 0 {
-		wg.Wait()
-		for key := range msgMap {
-			commitMsg := msgMap[key]
-			err = c.client.CommitMessage(ctx, reader, &commitMsg)
-			if err != nil {
-				logger.WithContext(ctx).Error("Failed to commit message", zap.Error(err))
-				return
-			}
-			delete(msgMap, key)
-			logger.WithContext(ctx).Info("Committed message",
-				zap.Int("partition", commitMsg.Partition),
-				zap.Int64("offset", commitMsg.Offset),
-				zap.String("key", string(commitMsg.Key)),
-				zap.String("value", string(commitMsg.Value)),
-			)
+	c.schedule(ctx, tracker, msg, schedulerMessageToDispatcher, schedulerProducer, harmonyProducer)
+}
+
+// schedule dispatches msg on a worker of the pool. A message due now waits for a free worker on the
+// fetch loop, so fetching pauses while all of them are busy. A re-enqueued collection waits for its
+// retry delay on its own, without holding a worker, and neither delays the messages fetched after
+// it nor the ones due before it. Fetching pauses while too many of them are waiting.
+func (c *Impl) schedule(ctx context.Context, tracker *OffsetTracker, msg kafka.Message, dispatcher Dispatcher,
+	schedulerProducer producer.Producer, harmonyProducer producer.Producer) {
+	tracker.Track(msg)
+	if dueIn(msg) <= 0 {
+		c.run(ctx, tracker, msg, dispatcher, schedulerProducer, harmonyProducer)
+		return
+	}
+	select {
+	case c.pending <- struct{}{}:
+	case <-ctx.Done():
+		tracker.Abandon(msg)
+		return
+	}
+	go func() {
+		defer func() { <-c.pending }()
+		// a re-enqueued collection is left uncommitted when the shutdown comes first, so it is redelivered
+		if err := waitUntilDue(ctx, msg); err != nil {
+			tracker.Abandon(msg)
+			return
+		}
+		c.run(ctx, tracker, msg, dispatcher, schedulerProducer, harmonyProducer)
+	}()
+}
+
+// run acquires a worker and dispatches the tracked msg on it.
+func (c *Impl) run(ctx context.Context, tracker *OffsetTracker, msg kafka.Message, dispatcher Dispatcher,
+	schedulerProducer producer.Producer, harmonyProducer producer.Producer) {
+	if err := c.pool.Acquire(ctx); err != nil {
+		tracker.Abandon(msg)
+		return
+	}
+	c.pool.Go(customerOf(msg), func() {
+		// a collection queued or cut short by the shutdown is not committed so it runs again after the restart,
+		// as is a message that could not be handed over to the dead-letter or retry topic
+		var err error
+		if ctx.Err() == nil {
+			err = c.dispatch(ctx, dispatcher, msg, schedulerProducer, harmonyProducer)
+		}
+		if ctx.Err() != nil || err != nil {
+			tracker.Abandon(msg)
+			return
 		}
+		tracker.Done(msg)
+	})
+}
+
+// dispatch runs dispatcher on msg, forwards the rejected messages to the dead-letter topic and
+// re-enqueues the failed collections. An error tells msg must not be committed.
+func (c *Impl) dispatch(ctx context.Context, dispatcher Dispatcher, msg kafka.Message,
+	schedulerProducer producer.Producer, harmonyProducer producer.Producer) error {
+	err := dispatcher(ctx, msg, schedulerProducer, harmonyProducer)
+	var rejected RejectedMessageError
+	var failed RetryableError
+	switch {
+	case errors.As(err, &rejected):
+		return c.forwardToDeadLetter(ctx, msg, rejected)
+	case errors.As(err, &failed) && ctx.Err() == nil:
+		return c.retry(ctx, msg, failed)
+	case err != nil:
+		logger.WithContext(ctx).Error("Failed to process message", zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset), zap.Error(err))
+	}
+	return nil
+}
+
+// forwardToDeadLetter writes a rejected message to the dead-letter topic with its original key,
+// headers and payload, and the reason in the DeadLetterReasonHeader header. Without a dead-letter
+// topic the message is dropped.
+func (c *Impl) forwardToDeadLetter(ctx context.Context, msg kafka.Message, rejected RejectedMessageError) error {
+	topic := configs.GetHaulerDeadLetterTopic()
+	if c.deadLetter == nil {
+		logger.WithContext(ctx).Warn("Dropping rejected message, no dead-letter topic",
+			zap.String("reason", rejected.Reason), zap.Int64("offset", msg.Offset))
+		prometheus.KafkaDeadLetterCnt.WithLabelValues(topic, rejected.Reason, droppedLabel).Inc()
+		return nil
+	}
+	reason, err := json.Marshal(DeadLetterReason{
+		Reason:    rejected.Reason,
+		Error:     rejected.Err.Error(),
+		Topic:     c.topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Time:      time.Now().UTC(),
+	})
+	if err == nil {
+		err = c.deadLetter.Forward(ctx, msg, kafka.Header{Key: DeadLetterReasonHeader, Value: reason})
+	}
+	if err != nil {
+		logger.WithContext(ctx).Error("Failed to forward message to the dead-letter topic",
+			zap.String("reason", rejected.Reason), zap.Int64("offset", msg.Offset), zap.Error(err))
+		prometheus.KafkaDeadLetterCnt.WithLabelValues(topic, rejected.Reason, ErrorLabel).Inc()
+		return err
 	}
+	prometheus.KafkaDeadLetterCnt.WithLabelValues(topic, rejected.Reason, SuccessLabel).Inc()
+	return nil
 }
 
 func getMessageHeader(message *kafka.Message, key string) string {
@@ -0,0 +1,189 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/services"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
+)
+
+// States of a duplicate collection request, set in the duplicate metric
+const (
+	runningLabel   = "running"
+	completedLabel = "completed"
+)
+
+var (
+	sharedDedupStore     *DedupStore
+	sharedDedupStoreOnce sync.Once
+)
+
+// SharedDedupStore returns the store of the configured TTL and file shared by every consumer of the
+// process. A file that cannot be read is logged and the store starts empty.
+func SharedDedupStore() *DedupStore {
+	sharedDedupStoreOnce.Do(func() {
+		store, err := NewDedupStore(configs.GetDedupTTL(), configs.GetDedupFile())
+		if err != nil {
+			logger.Error("Failed to load the collection results", zap.String("file", configs.GetDedupFile()),
+				zap.Error(err))
+		}
+		sharedDedupStore = store
+	})
+	return sharedDedupStore
+}
+
+type dedupEntry struct {
+	// Result is nil while the collection runs
+	Result *services.CollectionResult `json:"result,omitempty"`
+	Expiry time.Time                  `json:"expiry"`
+}
+
+// DedupStore remembers the collections processed by the hauler, keyed by collection ID and attempt,
+// so a redelivered or retried request is not collected twice. Entries expire after ttl. Results are
+// persisted to path, when set, and reloaded on start. Running collections are not, so one
+// interrupted by a restart runs again.
+type DedupStore struct {
+	ttl     time.Duration
+	path    string
+	mu      sync.Mutex
+	entries map[string]dedupEntry
+}
+
+func NewDedupStore(ttl time.Duration, path string) (*DedupStore, error) {
+	s := &DedupStore{ttl: ttl, path: path, entries: make(map[string]dedupEntry)}
+	if path == "" {
+		return s, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	entries := make(map[string]dedupEntry)
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return s, err
+	}
+	s.entries = entries
+	s.expire(time.Now())
+	return s, nil
+}
+
+// Begin marks a collection as running. It returns false when the collection already ran or is
+// running, with the original result or nil while it still runs.
+func (s *DedupStore) Begin(collectionID string) (*services.CollectionResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expire(now)
+	if entry, found := s.entries[collectionID]; found {
+		return entry.Result, false
+	}
+	s.entries[collectionID] = dedupEntry{Expiry: now.Add(s.ttl)}
+	return nil, true
+}
+
+// Complete records the result of a running collection. Failed collections are forgotten so a
+// retried request collects them again.
+func (s *DedupStore) Complete(ctx context.Context, collectionID string, result services.CollectionResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if result.Status == services.Failed {
+		delete(s.entries, collectionID)
+		return
+	}
+	s.entries[collectionID] = dedupEntry{Result: &result, Expiry: time.Now().Add(s.ttl)}
+	if err := s.persist(); err != nil {
+		logger.WithContext(ctx).Error("Failed to persist the collection results", zap.String("file", s.path),
+			zap.Error(err))
+	}
+}
+
+// CompleteAttempt records the result of an attempt of a collection, see dedupKey. A retry that did
+// not fail also replaces the result of the previous attempts, so the later requests for the
+// collection are answered with its latest outcome.
+func (s *DedupStore) CompleteAttempt(ctx context.Context, collectionID string, attempt int,
+	result services.CollectionResult) {
+	s.Complete(ctx, dedupKey(collectionID, attempt), result)
+	if attempt > 0 && result.Status != services.Failed {
+		s.Complete(ctx, collectionID, result)
+	}
+}
+
+// Release forgets a collection that stopped before completing, it has no effect on a completed one.
+func (s *DedupStore) Release(collectionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, found := s.entries[collectionID]; found && entry.Result == nil {
+		delete(s.entries, collectionID)
+	}
+}
+
+// dedupKey returns the key of an attempt of a collection. Every attempt is deduplicated on its own,
+// so a retry is not taken for a duplicate of the attempt that failed.
+func dedupKey(collectionID string, attempt int) string {
+	if attempt == 0 {
+		return collectionID
+	}
+	return fmt.Sprintf("%s/%d", collectionID, attempt)
+}
+
+func (s *DedupStore) expire(now time.Time) {
+	for collectionID, entry := range s.entries {
+		if now.After(entry.Expiry) {
+			delete(s.entries, collectionID)
+		}
+	}
+}
+
+// persist writes the completed collections through a temporary file, so a crash never leaves a
+// truncated one behind.
+func (s *DedupStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	completed := make(map[string]dedupEntry, len(s.entries))
+	for collectionID, entry := range s.entries {
+		if entry.Result != nil {
+			completed[collectionID] = entry
+		}
+	}
+	content, err := json.Marshal(completed)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// reportDuplicate answers a request for a collection that already ran with its original end of
+// collection event. A request for a running collection is skipped, the running one reports its result.
+func reportDuplicate(ctx context.Context, consumerDetails handlers.ConsumerDetails,
+	result *services.CollectionResult, schedulerProducer producer.Producer) {
+	topic := configs.GetHaulerConsumerKafkaTopic()
+	if result == nil {
+		logger.WithContext(ctx).Infof("Skipping collectionID %s, it is already running", consumerDetails.CollectionID)
+		prometheus.KafkaDuplicateCnt.WithLabelValues(topic, runningLabel).Inc()
+		return
+	}
+	logger.WithContext(ctx).Infof("CollectionID %s already completed with status %s, reporting its result",
+		consumerDetails.CollectionID, result.Status)
+	services.PublishEndOfCollection(ctx, consumerDetails, *result, schedulerProducer)
+	prometheus.KafkaDuplicateCnt.WithLabelValues(topic, completedLabel).Inc()
+}
@@ -0,0 +1,136 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/services"
+)
+
+type fakeProducer struct {
+	messages [][]byte
+}
+
+func (p *fakeProducer) CloseWriter() error {
+	return nil
+}
+
+func (p *fakeProducer) PublishMessage(_ context.Context, _, _, _, _ string, _ time.Time, data []byte) error {
+	p.messages = append(p.messages, data)
+	return nil
+}
+
+func TestDedupStoreDuplicates(t *testing.T) {
+	store, err := NewDedupStore(time.Hour, "")
+	require.NoError(t, err)
+
+	_, first := store.Begin("c1")
+	assert.True(t, first)
+	result, first := store.Begin("c1")
+	assert.False(t, first, "the collection is running")
+	assert.Nil(t, result)
+
+	store.Complete(context.Background(), "c1", services.CollectionResult{Status: services.Partial, ManifestKey: "m"})
+	store.Release("c1")
+	result, first = store.Begin("c1")
+	assert.False(t, first)
+	require.NotNil(t, result)
+	assert.Equal(t, "m", result.ManifestKey)
+
+	_, first = store.Begin("c2")
+	require.True(t, first)
+	store.Complete(context.Background(), "c2", services.CollectionResult{Status: services.Failed})
+	_, first = store.Begin("c2")
+	assert.True(t, first, "failed collections run again")
+
+	store.Release("c2")
+	_, first = store.Begin("c2")
+	assert.True(t, first, "released collections run again")
+}
+
+func TestDedupStoreRetryReplacesPartialResult(t *testing.T) {
+	store, err := NewDedupStore(time.Hour, "")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, first := store.Begin(dedupKey("c1", 0))
+	require.True(t, first)
+	store.CompleteAttempt(ctx, "c1", 0, services.CollectionResult{Status: services.Partial, ManifestKey: "m0"})
+
+	_, first = store.Begin(dedupKey("c1", 1))
+	require.True(t, first, "a retry is not taken for a duplicate")
+	store.CompleteAttempt(ctx, "c1", 1, services.CollectionResult{Status: services.Failed})
+	result, _ := store.Begin("c1")
+	require.NotNil(t, result)
+	assert.Equal(t, services.Partial, result.Status, "a failed retry keeps the previous result")
+
+	_, first = store.Begin(dedupKey("c1", 2))
+	require.True(t, first)
+	store.CompleteAttempt(ctx, "c1", 2, services.CollectionResult{Status: services.Success, ManifestKey: "m2"})
+	result, first = store.Begin("c1")
+	assert.False(t, first)
+	require.NotNil(t, result)
+	assert.Equal(t, services.Success, result.Status, "a duplicate request gets the outcome of the retry")
+	assert.Equal(t, "m2", result.ManifestKey)
+}
+
+func TestDedupStoreExpiry(t *testing.T) {
+	store, err := NewDedupStore(time.Millisecond, "")
+	require.NoError(t, err)
+	_, first := store.Begin("c1")
+	require.True(t, first)
+	store.Complete(context.Background(), "c1", services.CollectionResult{Status: services.Success})
+
+	time.Sleep(5 * time.Millisecond)
+	_, first = store.Begin("c1")
+	assert.True(t, first)
+}
+
+func TestDedupStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collections.json")
+	store, err := NewDedupStore(time.Hour, path)
+	require.NoError(t, err)
+	store.Begin("completed")
+	store.Begin("running")
+	store.Complete(context.Background(), "completed", services.CollectionResult{Status: services.Success,
+		ManifestKey: "m", ManifestSize: 3})
+
+	reloaded, err := NewDedupStore(time.Hour, path)
+	require.NoError(t, err)
+	result, first := reloaded.Begin("completed")
+	assert.False(t, first)
+	require.NotNil(t, result)
+	assert.Equal(t, services.CollectionResult{Status: services.Success, ManifestKey: "m", ManifestSize: 3,
+		EndTime: result.EndTime}, *result)
+	_, first = reloaded.Begin("running")
+	assert.True(t, first, "interrupted collections run again after a restart")
+}
+
+func TestReportDuplicate(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	scheduler := &fakeProducer{}
+	details := handlers.ConsumerDetails{CollectionID: "c1", CollectionType: "Inventory"}
+
+	reportDuplicate(context.Background(), details, nil, scheduler)
+	assert.Empty(t, scheduler.messages, "the running collection reports its own result")
+
+	reportDuplicate(context.Background(), details, &services.CollectionResult{Status: services.Partial,
+		UploadStatus: services.Success, ManifestKey: "m", ManifestSize: 3}, scheduler)
+	require.Len(t, scheduler.messages, 1)
+	var eoc model.ScheduleStatus
+	require.NoError(t, json.Unmarshal(scheduler.messages[0], &eoc))
+	assert.Equal(t, handlers.EndOfCollection, eoc.CollectionType)
+	assert.Equal(t, services.Partial, eoc.CollectionStatus)
+	assert.Equal(t, "m", eoc.FileName)
+	assert.Equal(t, "3", eoc.UploadFileSize)
+}
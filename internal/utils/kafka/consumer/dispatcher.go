@@ -5,11 +5,13 @@ package consumer
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/segmentio/kafka-go"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/sink"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/services"
@@ -21,20 +23,74 @@ import (
 const (
 	ErrorLabel      = "error"
 	SuccessLabel    = "success"
+	droppedLabel    = "dropped"
 	EndOfCollection = "EOC"
 )
 
+// Reasons a collection request is rejected, set in the dead-letter header
+const (
+	ReasonMissingType             = "missing_type"
+	ReasonMissingCollectionID     = "missing_collection_id"
+	ReasonInvalidJSON             = "invalid_json"
+	ReasonInvalidInput            = "invalid_input"
+	ReasonUnsupportedCollection   = "unsupported_collection_type"
+	ReasonUnsupportedOutputFormat = "unsupported_output_format"
+	ReasonMaxAttempts             = "max_attempts_exceeded"
+)
+
 var (
-	errEmptyID = errors.New("mandatory field is empty")
-	json       = jsoniter.ConfigCompatibleWithStandardLibrary
+	errEmptyID                 = errors.New("mandatory field is empty")
+	errMissingHeader           = errors.New("mandatory header is missing")
+	errUnsupportedCollection   = errors.New("unsupported collection type")
+	errUnsupportedOutputFormat = errors.New("unsupported output format")
+	errIncompleteCollection    = errors.New("collection did not complete")
+	json                       = jsoniter.ConfigCompatibleWithStandardLibrary
 )
 
+// RejectedMessageError reports a collection request that can never be processed, it is
+// forwarded to the dead-letter topic.
+type RejectedMessageError struct {
+	Reason string
+	Err    error
+}
+
+func (e RejectedMessageError) Error() string {
+	return fmt.Sprintf("collection request rejected (%s): %v", e.Reason, e.Err)
+}
+
+func (e RejectedMessageError) Unwrap() error {
+	return e.Err
+}
+
+// RetryableError reports a collection that failed, entirely or partially, and may succeed when
+// attempted again.
+type RetryableError struct {
+	Status string
+	Err    error
+}
+
+func (e RetryableError) Error() string {
+	return fmt.Sprintf("collection status %s: %v", e.Status, e.Err)
+}
+
+func (e RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Dispatcher processes a collection request. It returns a RejectedMessageError when the
+// request is malformed and a RetryableError when the collection did not succeed.
 type Dispatcher func(
 	context.Context,
 	kafka.Message,
 	producer.Producer,
 	producer.Producer,
-)
+) error
+
+// reject counts a malformed request and returns the error handing it over to the dead-letter topic.
+func reject(reason string, err error) error {
+	prometheus.KafkaConsumerEventsCnt.WithLabelValues(configs.GetHaulerConsumerKafkaTopic(), ErrorLabel).Inc()
+	return RejectedMessageError{Reason: reason, Err: err}
+}
 
 func validateSchedulerInput(ctx context.Context, input *model.KafkaCollectionRequest) error {
 	if input.ApplicationCustomerID == "" || input.PlatformCustomerID == "" ||
@@ -55,44 +111,48 @@ func validateSchedulerInput(ctx context.Context, input *model.KafkaCollectionReq
 
 //nolint:funlen,dupl // required
 func dispatchMessage(ctx context.Context, msg kafka.Message, schedulerProducer producer.Producer,
-	harmonyProducer producer.Producer) {
-	defer wg.Done()
+	harmonyProducer producer.Producer) error {
 	grpcContext, grpcCancel := context.WithCancel(ctx)
 	defer grpcCancel()
 
 	messageType := getMessageHeader(&msg, "ce_type")
 	if messageType == "" {
 		logger.WithContext(grpcContext).Error("Ignoring message with unspecified type")
-		prometheus.KafkaConsumerEventsCnt.WithLabelValues(configs.GetHaulerConsumerKafkaTopic(), ErrorLabel).Inc()
-		return
+		return reject(ReasonMissingType, fmt.Errorf("%w: ce_type", errMissingHeader))
 	}
 
 	cID := getMessageHeader(&msg, "ce_collectionid")
 	if cID == "" {
 		logger.WithContext(grpcContext).Error("Ignoring message with missing ce_collectionid")
-		prometheus.KafkaConsumerEventsCnt.WithLabelValues(configs.GetHaulerConsumerKafkaTopic(), ErrorLabel).Inc()
-		return
+		return reject(ReasonMissingCollectionID, fmt.Errorf("%w: ce_collectionid", errMissingHeader))
 	}
 
 	data := &model.KafkaCollectionRequest{}
 	err := json.Unmarshal(msg.Value, &data)
 	if err != nil {
 		logger.WithContext(grpcContext).Error(err.Error(), err)
-		prometheus.KafkaConsumerEventsCnt.WithLabelValues(configs.GetHaulerConsumerKafkaTopic(), ErrorLabel).Inc()
-		return
+		return reject(ReasonInvalidJSON, err)
 	}
 	err = validateSchedulerInput(grpcContext, data)
 	if err != nil {
 		logger.WithContext(grpcContext).Error("Invalid input", zap.Error(err))
-		prometheus.KafkaConsumerEventsCnt.WithLabelValues(configs.GetHaulerConsumerKafkaTopic(), ErrorLabel).Inc()
-		return
+		return reject(ReasonInvalidInput, err)
 	}
 
 	validCollectionTypes := []string{"Inventory", "Volume-Perf"}
 	if !handlers.Contains(validCollectionTypes, data.CollectionType) {
 		logger.WithContext(grpcContext).Error("Invalid collection type specified")
-		prometheus.KafkaConsumerEventsCnt.WithLabelValues(configs.GetHaulerConsumerKafkaTopic(), ErrorLabel).Inc()
-		return
+		return reject(ReasonUnsupportedCollection,
+			fmt.Errorf("%w: %s", errUnsupportedCollection, data.CollectionType))
+	}
+
+	if data.OutputFormat == "" {
+		data.OutputFormat = configs.GetOutputFormat()
+	}
+	if !handlers.Contains(handlers.OutputFormats, data.OutputFormat) {
+		logger.WithContext(grpcContext).Errorf("Invalid output format %s specified", data.OutputFormat)
+		return reject(ReasonUnsupportedOutputFormat,
+			fmt.Errorf("%w: %s", errUnsupportedOutputFormat, data.OutputFormat))
 	}
 
 	var consumerDetails = &handlers.ConsumerDetails{
@@ -103,38 +163,52 @@ func dispatchMessage(ctx context.Context, msg kafka.Message, schedulerProducer p
 		ApplicationInstanceID: data.ApplicationInstanceID,
 		CollectionTrigger:     data.CollectionTrigger,
 		CollectionType:        data.CollectionType,
+		OutputFormat:          data.OutputFormat,
 	}
 
 	// metrics on total consumer events count and per customerID events count
 	prometheus.KafkaConsumerEventsCnt.WithLabelValues(configs.GetHaulerConsumerKafkaTopic(), SuccessLabel).Inc()
 
-	// var errorMap = make(map[string]map[string]string)
-	// var statusMap = make(map[string]string)
-	// var collectionStartTime = time.Now().UTC().String()
-	// var errorMapEOC = make(map[string]map[string]string)
+	attempt := RetryAttempt(msg)
+	key := dedupKey(cID, attempt)
+	dedupStore := SharedDedupStore()
+	if result, first := dedupStore.Begin(key); !first {
+		reportDuplicate(grpcContext, *consumerDetails, result, schedulerProducer)
+		return nil
+	}
+	// forgets the collection unless it completed, so a redelivered request runs it
+	defer dedupStore.Release(key)
+
+	fileSink, err := sink.Default(grpcContext)
+	if err != nil {
+		logger.WithContext(grpcContext).Errorf("Sink initiation failed %v", err)
+		return RetryableError{Status: services.Failed, Err: err}
+	}
 
-	assetClient, assetClientErr := commonclient.NewCommonClient(grpcContext)
-	if assetClient == nil {
+	assetClient, assetClientErr := commonclient.NewCommonClient(grpcContext, data.ApplicationCustomerID,
+		data.PlatformCustomerID)
+	if assetClientErr != nil {
 		logger.WithContext(grpcContext).Errorf("Client initiation failed %v", assetClientErr)
-		// handlers.SetNested(errorMap, handlers.RESTError, handlers.RESTError, arcusClientErr.Error())
-		// var data = handlers.ConstructFailedCollectionJSON(*consumerDetails, collectionStartTime,
-		// 	handlers.UnknownDeviceType, handlers.ArcusHaulertype, errorMap)
-		// file, _ := json.MarshalIndent(data, "", " ")
-		// handlers.UploadToServer(grpcContext, file, *consumerDetails, services.Common, errorMap, schedulerProducer,
-		// 	harmonyProducer)
-		// consumerDetails.CollectionType = EndOfCollection
-		// var eoc = handlers.ConstructEOCIndicatorJSON(*consumerDetails, collectionStartTime, handlers.ArcusHaulertype,
-		// 	statusMap)
-		// eocJSON, _ := json.MarshalIndent(eoc, "", " ")
-		// handlers.UploadFileToServer(grpcContext, eocJSON, *consumerDetails, handlers.ArcusHaulertype, handlers.UnknownDeviceType,
-		// 	statusMap, errorMapEOC, schedulerProducer, harmonyProducer)
-		return
+		var errorMap = make(map[string]map[string]string)
+		handlers.SetNested(errorMap, handlers.RESTError, handlers.RESTError, assetClientErr.Error())
+		services.PublishFailedCollection(grpcContext, *consumerDetails, errorMap, schedulerProducer)
+		return RetryableError{Status: services.Failed, Err: assetClientErr}
 	}
 
-	dataCollectionService := services.NewDataCollectionService(grpcContext, assetClient)
+	dataCollectionService := services.NewDataCollectionService(grpcContext, assetClient, fileSink)
 	if dataCollectionService != nil {
 		logger.WithContext(grpcContext).Infof("Collection started for collectionID: %s", consumerDetails.CollectionID)
-		dataCollectionService.CollectDeviceInformation(grpcContext, *consumerDetails, schedulerProducer, harmonyProducer)
+		result := dataCollectionService.CollectDeviceInformation(grpcContext, *consumerDetails, schedulerProducer,
+			harmonyProducer)
+		// a collection interrupted by the shutdown is redelivered and runs again
+		if ctx.Err() == nil {
+			dedupStore.CompleteAttempt(grpcContext, cID, attempt, result)
+		}
 		logger.WithContext(grpcContext).Infof("Collection complete for collectionID: %s", consumerDetails.CollectionID)
+		if result.Status != services.Success {
+			return RetryableError{Status: result.Status, Err: fmt.Errorf("%w: %s", errIncompleteCollection,
+				result.Errors)}
+		}
 	}
+	return nil
 }
@@ -0,0 +1,153 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/sink"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/services"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
+)
+
+type fakeForwarder struct {
+	forwarded []kafka.Message
+	headers   [][]kafka.Header
+	err       error
+}
+
+func (f *fakeForwarder) CloseWriter() error {
+	return nil
+}
+
+func (f *fakeForwarder) Forward(_ context.Context, msg kafka.Message, headers ...kafka.Header) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.forwarded = append(f.forwarded, msg)
+	f.headers = append(f.headers, headers)
+	return nil
+}
+
+func collectionRequest(value string, headers ...string) kafka.Message {
+	msg := kafka.Message{Value: []byte(value)}
+	for i := 0; i+1 < len(headers); i += 2 {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: headers[i], Value: []byte(headers[i+1])})
+	}
+	return msg
+}
+
+const validRequest = `{"platform_customer_id":"p","application_customer_id":"a","collection_id":"c",
+	"collection_type":"Inventory","collection_trigger":"t","region":"r"}`
+
+func TestDispatchMessageRejections(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	for _, test := range []struct {
+		name   string
+		msg    kafka.Message
+		reason string
+	}{
+		{"missing type", collectionRequest(validRequest), ReasonMissingType},
+		{"missing collection ID", collectionRequest(validRequest, "ce_type", "t"), ReasonMissingCollectionID},
+		{"invalid JSON", collectionRequest(`{"collection_id":`, "ce_type", "t", "ce_collectionid", "c"),
+			ReasonInvalidJSON},
+		{"invalid input", collectionRequest(`{"collection_id":"c"}`, "ce_type", "t", "ce_collectionid", "c"),
+			ReasonInvalidInput},
+		{"unsupported collection type", collectionRequest(`{"platform_customer_id":"p","application_customer_id":"a",
+			"collection_id":"c","collection_type":"Other","collection_trigger":"t","region":"r"}`,
+			"ce_type", "t", "ce_collectionid", "c"), ReasonUnsupportedCollection},
+		{"unsupported output format", collectionRequest(`{"platform_customer_id":"p","application_customer_id":"a",
+			"collection_id":"c","collection_type":"Inventory","collection_trigger":"t","region":"r",
+			"output_format":"xml"}`, "ce_type", "t", "ce_collectionid", "c"), ReasonUnsupportedOutputFormat},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := dispatchMessage(context.Background(), test.msg, nil, nil)
+			var rejected RejectedMessageError
+			require.True(t, errors.As(err, &rejected), "%v", err)
+			assert.Equal(t, test.reason, rejected.Reason)
+		})
+	}
+}
+
+func TestDispatchForwardsRejectedMessages(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	deadLetter := &fakeForwarder{}
+	c := &Impl{topic: "collection", deadLetter: deadLetter}
+	msg := collectionRequest(`{"collection_id":`, "ce_type", "t", "ce_collectionid", "c")
+	msg.Partition, msg.Offset = 1, 42
+
+	c.dispatch(context.Background(), dispatchMessage, msg, nil, nil)
+	require.Len(t, deadLetter.forwarded, 1)
+	assert.Equal(t, msg, deadLetter.forwarded[0])
+	require.Len(t, deadLetter.headers[0], 1)
+	assert.Equal(t, DeadLetterReasonHeader, deadLetter.headers[0][0].Key)
+	var reason DeadLetterReason
+	require.NoError(t, json.Unmarshal(deadLetter.headers[0][0].Value, &reason))
+	assert.Equal(t, ReasonInvalidJSON, reason.Reason)
+	assert.NotEmpty(t, reason.Error)
+	assert.Equal(t, "collection", reason.Topic)
+	assert.Equal(t, 1, reason.Partition)
+	assert.Equal(t, int64(42), reason.Offset)
+
+	processed := func(context.Context, kafka.Message, producer.Producer, producer.Producer) error {
+		return errors.New("collection failed")
+	}
+	c.dispatch(context.Background(), processed, msg, nil, nil)
+	assert.Len(t, deadLetter.forwarded, 1, "only rejected messages are dead-lettered")
+
+	deadLetter.err = errors.New("broker down")
+	c.dispatch(context.Background(), dispatchMessage, msg, nil, nil)
+	(&Impl{}).dispatch(context.Background(), dispatchMessage, msg, nil, nil)
+}
+
+func TestRejectedMessageNotCommittedWhenDeadLetterFails(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	deadLetter := &fakeForwarder{err: errors.New("broker down")}
+	c := &Impl{topic: "collection", deadLetter: deadLetter, pool: NewWorkerPool("collection", 1, false)}
+	tracker := NewOffsetTracker("collection")
+	msg := collectionRequest(`{"collection_id":`, "ce_type", "t", "ce_collectionid", "c")
+	msg.Offset = 42
+
+	tracker.Track(msg)
+	c.run(context.Background(), tracker, msg, dispatchMessage, nil, nil)
+	tracker.Wait()
+	assert.Empty(t, tracker.Ready(), "the rejected message is redelivered")
+
+	deadLetter.err = nil
+	msg.Partition = 1
+	tracker.Track(msg)
+	c.run(context.Background(), tracker, msg, dispatchMessage, nil, nil)
+	tracker.Wait()
+	require.Len(t, tracker.Ready(), 1)
+	assert.Equal(t, 1, tracker.Ready()[0].Partition)
+}
+
+func TestDispatchMessageUnknownTenant(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	viper.Set("sink_type", sink.LocalType)
+	viper.Set("sink_local_dir", t.TempDir())
+	viper.Set("credentials_dir", t.TempDir())
+	t.Cleanup(func() { viper.Set("credentials_dir", "") })
+	scheduler := &fakeProducer{}
+
+	err := dispatchMessage(context.Background(),
+		collectionRequest(validRequest, "ce_type", "t", "ce_collectionid", "unknown-tenant"), scheduler, nil)
+	var failed RetryableError
+	require.True(t, errors.As(err, &failed), "%v", err)
+	assert.Equal(t, services.Failed, failed.Status)
+	require.Len(t, scheduler.messages, 2, "a failed status and the end of collection are published")
+	var eoc model.ScheduleStatus
+	require.NoError(t, json.Unmarshal(scheduler.messages[1], &eoc))
+	assert.Equal(t, handlers.EndOfCollection, eoc.CollectionType)
+	assert.Equal(t, services.Failed, eoc.CollectionStatus)
+}
@@ -0,0 +1,124 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+// partitionOffsets is the progress of the messages fetched from a partition.
+type partitionOffsets struct {
+	// inFlight are the offsets not committable yet, in fetch order
+	inFlight []int64
+	// completed are the messages processed while an earlier offset is still in flight
+	completed map[int64]kafka.Message
+	// ready is the highest contiguous completed message, not committed yet
+	ready *kafka.Message
+	// next is the offset the partition resumes from after a restart
+	next          int64
+	highWaterMark int64
+}
+
+// OffsetTracker follows the messages dispatched concurrently and tells, per partition, the highest
+// offset whose message and all preceding ones are processed. Committing it never skips a message
+// still being processed, which is redelivered after a restart instead.
+type OffsetTracker struct {
+	topic      string
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+	wg         sync.WaitGroup
+}
+
+func NewOffsetTracker(topic string) *OffsetTracker {
+	return &OffsetTracker{topic: topic, partitions: make(map[int]*partitionOffsets)}
+}
+
+// Track registers a fetched message before it is dispatched.
+func (t *OffsetTracker) Track(msg kafka.Message) {
+	t.wg.Add(1)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, found := t.partitions[msg.Partition]
+	if !found {
+		p = &partitionOffsets{completed: make(map[int64]kafka.Message), next: msg.Offset}
+		t.partitions[msg.Partition] = p
+	}
+	p.inFlight = append(p.inFlight, msg.Offset)
+	if msg.HighWaterMark > p.highWaterMark {
+		p.highWaterMark = msg.HighWaterMark
+	}
+	t.updateLag(msg.Partition, p)
+}
+
+// Done marks a tracked message as processed.
+func (t *OffsetTracker) Done(msg kafka.Message) {
+	defer t.wg.Done()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partitions[msg.Partition]
+	p.completed[msg.Offset] = msg
+	for len(p.inFlight) > 0 {
+		completed, found := p.completed[p.inFlight[0]]
+		if !found {
+			break
+		}
+		delete(p.completed, completed.Offset)
+		p.ready = &completed
+		p.inFlight = p.inFlight[1:]
+	}
+}
+
+// Abandon releases a tracked message whose processing was interrupted. It is never committed,
+// nor are the following messages of its partition.
+func (t *OffsetTracker) Abandon(kafka.Message) {
+	t.wg.Done()
+}
+
+// Wait blocks until every tracked message is done or abandoned.
+func (t *OffsetTracker) Wait() {
+	t.wg.Wait()
+}
+
+// Ready returns the highest contiguous processed message of every partition that has not been
+// committed yet, ordered by partition.
+func (t *OffsetTracker) Ready() []kafka.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var ready []kafka.Message
+	for _, p := range t.partitions {
+		if p.ready != nil {
+			ready = append(ready, *p.ready)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].Partition < ready[j].Partition })
+	return ready
+}
+
+// Committed records that msg, returned by Ready, has been committed.
+func (t *OffsetTracker) Committed(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partitions[msg.Partition]
+	if p.ready != nil && p.ready.Offset == msg.Offset {
+		p.ready = nil
+	}
+	if msg.Offset+1 > p.next {
+		p.next = msg.Offset + 1
+	}
+	t.updateLag(msg.Partition, p)
+}
+
+// updateLag exports the number of messages of the partition not committed yet.
+func (t *OffsetTracker) updateLag(partition int, p *partitionOffsets) {
+	lag := p.highWaterMark - p.next
+	if lag < 0 {
+		lag = 0
+	}
+	prometheus.KafkaConsumerLag.WithLabelValues(t.topic, strconv.Itoa(partition)).Set(float64(lag))
+}
@@ -0,0 +1,78 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+func fetched(partition int, offset int64) kafka.Message {
+	return kafka.Message{Partition: partition, Offset: offset, HighWaterMark: 10}
+}
+
+func readyOffsets(tracker *OffsetTracker) map[int]int64 {
+	offsets := make(map[int]int64)
+	for _, msg := range tracker.Ready() {
+		offsets[msg.Partition] = msg.Offset
+	}
+	return offsets
+}
+
+func TestOffsetTrackerCommitsContiguousOffsets(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	tracker := NewOffsetTracker("collection")
+	for offset := int64(0); offset < 3; offset++ {
+		tracker.Track(fetched(0, offset))
+	}
+	tracker.Track(fetched(1, 7))
+
+	tracker.Done(fetched(0, 2))
+	tracker.Done(fetched(1, 7))
+	assert.Equal(t, map[int]int64{1: 7}, readyOffsets(tracker), "offset 2 completed before 0 and 1")
+
+	tracker.Done(fetched(0, 0))
+	assert.Equal(t, map[int]int64{0: 0, 1: 7}, readyOffsets(tracker))
+
+	tracker.Done(fetched(0, 1))
+	assert.Equal(t, map[int]int64{0: 2, 1: 7}, readyOffsets(tracker))
+	tracker.Wait()
+}
+
+func TestOffsetTrackerAbandonBlocksLaterOffsets(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	tracker := NewOffsetTracker("collection")
+	tracker.Track(fetched(0, 4))
+	tracker.Track(fetched(0, 5))
+	tracker.Track(fetched(0, 6))
+
+	tracker.Done(fetched(0, 4))
+	tracker.Abandon(fetched(0, 5))
+	tracker.Done(fetched(0, 6))
+	tracker.Wait()
+
+	assert.Equal(t, map[int]int64{0: 4}, readyOffsets(tracker))
+}
+
+func TestOffsetTrackerCommitted(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	tracker := NewOffsetTracker("collection")
+	tracker.Track(fetched(0, 0))
+	tracker.Track(fetched(0, 1))
+	tracker.Done(fetched(0, 0))
+
+	ready := tracker.Ready()
+	require.Len(t, ready, 1)
+	tracker.Committed(ready[0])
+	assert.Empty(t, tracker.Ready())
+	assert.Equal(t, int64(1), tracker.partitions[0].next)
+
+	tracker.Done(fetched(0, 1))
+	assert.Equal(t, map[int]int64{0: 1}, readyOffsets(tracker))
+	tracker.Wait()
+}
@@ -0,0 +1,135 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+)
+
+const (
+	// RetryAttemptHeader holds the number of failed attempts of a re-enqueued collection request
+	RetryAttemptHeader = "retry_attempt"
+	// RetryNotBeforeHeader holds the time, in RFC 3339, a re-enqueued collection request may run again
+	RetryNotBeforeHeader = "retry_not_before"
+	exhaustedLabel       = "exhausted"
+)
+
+// CollectionRetryPolicy tells how many times a collection is attempted and how long the retries wait.
+type CollectionRetryPolicy struct {
+	// MaxAttempts includes the first attempt
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubling on every retry
+	BaseDelay time.Duration
+}
+
+// CollectionRetryPolicyFromConfig returns the configured collection retry policy.
+func CollectionRetryPolicyFromConfig() CollectionRetryPolicy {
+	return CollectionRetryPolicy{
+		MaxAttempts: configs.GetCollectionMaxAttempts(),
+		BaseDelay:   configs.GetCollectionRetryDelay(),
+	}
+}
+
+// Delay returns how long to wait before retrying a collection that failed attempt times.
+func (p CollectionRetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// RetryAttempt returns the number of failed attempts of a collection request, 0 for a new one.
+func RetryAttempt(msg kafka.Message) int {
+	attempt, err := strconv.Atoi(getMessageHeader(&msg, RetryAttemptHeader))
+	if err != nil {
+		return 0
+	}
+	return attempt
+}
+
+func newPendingRetries(size int) chan struct{} {
+	if size < 1 {
+		size = 1
+	}
+	return make(chan struct{}, size)
+}
+
+// dueIn returns how long a re-enqueued collection request waits before running again, not
+// positive once it is due or for a new request.
+func dueIn(msg kafka.Message) time.Duration {
+	notBefore, err := time.Parse(time.RFC3339Nano, getMessageHeader(&msg, RetryNotBeforeHeader))
+	if err != nil {
+		return 0
+	}
+	return time.Until(notBefore)
+}
+
+// waitUntilDue blocks until a re-enqueued collection request may run again, or ctx is done.
+func waitUntilDue(ctx context.Context, msg kafka.Message) error {
+	wait := dueIn(msg)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// withoutRetryHeaders returns msg without the headers of a previous attempt.
+func withoutRetryHeaders(msg kafka.Message) kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for _, header := range msg.Headers {
+		if header.Key != RetryAttemptHeader && header.Key != RetryNotBeforeHeader {
+			headers = append(headers, header)
+		}
+	}
+	msg.Headers = headers
+	return msg
+}
+
+// retry re-enqueues a failed collection request on the retry topic with its attempt count and the
+// time it may run again. Requests failing MaxAttempts times are forwarded to the dead-letter topic.
+// An error tells the request could not be handed over and must not be committed.
+func (c *Impl) retry(ctx context.Context, msg kafka.Message, failed RetryableError) error {
+	if c.retryForwarder == nil {
+		logger.WithContext(ctx).Error("Collection failed, retries are disabled", zap.Int64("offset", msg.Offset),
+			zap.Error(failed))
+		return nil
+	}
+	topic := configs.GetHaulerRetryTopic()
+	attempt := RetryAttempt(msg) + 1
+	if attempt >= c.retryPolicy.MaxAttempts {
+		logger.WithContext(ctx).Error("Collection failed on every attempt", zap.Int("attempts", attempt),
+			zap.Int64("offset", msg.Offset), zap.Error(failed))
+		prometheus.KafkaRetryCnt.WithLabelValues(topic, exhaustedLabel).Inc()
+		return c.forwardToDeadLetter(ctx, msg, RejectedMessageError{Reason: ReasonMaxAttempts, Err: failed})
+	}
+	notBefore := time.Now().Add(c.retryPolicy.Delay(attempt)).UTC()
+	err := c.retryForwarder.Forward(ctx, withoutRetryHeaders(msg),
+		kafka.Header{Key: RetryAttemptHeader, Value: []byte(strconv.Itoa(attempt))},
+		kafka.Header{Key: RetryNotBeforeHeader, Value: []byte(notBefore.Format(time.RFC3339Nano))})
+	if err != nil {
+		logger.WithContext(ctx).Error("Failed to re-enqueue the collection", zap.Int("attempt", attempt),
+			zap.Int64("offset", msg.Offset), zap.Error(err))
+		prometheus.KafkaRetryCnt.WithLabelValues(topic, ErrorLabel).Inc()
+		return err
+	}
+	logger.WithContext(ctx).Info("Collection re-enqueued", zap.Int("attempt", attempt),
+		zap.Time("notBefore", notBefore), zap.Error(failed))
+	prometheus.KafkaRetryCnt.WithLabelValues(topic, SuccessLabel).Inc()
+	return nil
+}
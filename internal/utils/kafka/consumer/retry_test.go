@@ -0,0 +1,182 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/services"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/producer"
+)
+
+var errCollection = errors.New("auth failed")
+
+func failingDispatcher(context.Context, kafka.Message, producer.Producer, producer.Producer) error {
+	return RetryableError{Status: services.Failed, Err: errCollection}
+}
+
+func headerValues(headers []kafka.Header) map[string]string {
+	values := make(map[string]string, len(headers))
+	for _, header := range headers {
+		values[header.Key] = string(header.Value)
+	}
+	return values
+}
+
+func TestCollectionRetryPolicyDelay(t *testing.T) {
+	policy := CollectionRetryPolicy{MaxAttempts: 4, BaseDelay: time.Minute}
+	assert.Equal(t, time.Minute, policy.Delay(1))
+	assert.Equal(t, 2*time.Minute, policy.Delay(2))
+	assert.Equal(t, 4*time.Minute, policy.Delay(3))
+}
+
+func TestRetryReEnqueuesFailedCollections(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	retry := &fakeForwarder{}
+	c := &Impl{topic: "collection", retryForwarder: retry,
+		retryPolicy: CollectionRetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute}}
+	msg := collectionRequest(validRequest, "ce_collectionid", "c", RetryAttemptHeader, "1",
+		RetryNotBeforeHeader, "2023-01-01T00:00:00Z")
+
+	before := time.Now()
+	c.dispatch(context.Background(), failingDispatcher, msg, nil, nil)
+
+	require.Len(t, retry.forwarded, 1)
+	assert.Equal(t, map[string]string{"ce_collectionid": "c"}, headerValues(retry.forwarded[0].Headers),
+		"the headers of the previous attempt are dropped")
+	headers := headerValues(retry.headers[0])
+	assert.Equal(t, "2", headers[RetryAttemptHeader])
+	notBefore, err := time.Parse(time.RFC3339Nano, headers[RetryNotBeforeHeader])
+	require.NoError(t, err)
+	assert.WithinDuration(t, before.Add(2*time.Minute), notBefore, time.Second)
+}
+
+func TestRetryForwardsExhaustedCollectionsToDeadLetter(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	retry, deadLetter := &fakeForwarder{}, &fakeForwarder{}
+	c := &Impl{topic: "collection", retryForwarder: retry, deadLetter: deadLetter,
+		retryPolicy: CollectionRetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute}}
+
+	c.dispatch(context.Background(), failingDispatcher, collectionRequest(validRequest, RetryAttemptHeader, "2"),
+		nil, nil)
+
+	assert.Empty(t, retry.forwarded)
+	require.Len(t, deadLetter.forwarded, 1)
+	var reason DeadLetterReason
+	require.NoError(t, json.Unmarshal(deadLetter.headers[0][0].Value, &reason))
+	assert.Equal(t, ReasonMaxAttempts, reason.Reason)
+	assert.Contains(t, reason.Error, errCollection.Error())
+}
+
+func TestRetrySkippedOnShutdown(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	retry := &fakeForwarder{}
+	c := &Impl{topic: "collection", retryForwarder: retry,
+		retryPolicy: CollectionRetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c.dispatch(ctx, failingDispatcher, collectionRequest(validRequest), nil, nil)
+	assert.Empty(t, retry.forwarded, "an interrupted collection is redelivered instead")
+}
+
+func TestFailedCollectionNotCommittedWhenRetryFails(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	retry := &fakeForwarder{err: errors.New("broker down")}
+	c := &Impl{topic: "collection", retryForwarder: retry, pool: NewWorkerPool("collection", 1, false),
+		retryPolicy: CollectionRetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute}}
+	tracker := NewOffsetTracker("collection")
+	msg := collectionRequest(validRequest)
+	msg.Offset = 42
+
+	tracker.Track(msg)
+	c.run(context.Background(), tracker, msg, failingDispatcher, nil, nil)
+	tracker.Wait()
+	assert.Empty(t, tracker.Ready(), "the failed collection is redelivered")
+}
+
+func TestWaitUntilDue(t *testing.T) {
+	assert.NoError(t, waitUntilDue(context.Background(), collectionRequest(validRequest)))
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339Nano)
+	assert.NoError(t, waitUntilDue(context.Background(), collectionRequest(validRequest, RetryNotBeforeHeader, past)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	future := time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	assert.ErrorIs(t, waitUntilDue(ctx, collectionRequest(validRequest, RetryNotBeforeHeader, future)),
+		context.DeadlineExceeded)
+	assert.Equal(t, 0, RetryAttempt(collectionRequest(validRequest)))
+}
+
+func TestScheduleDoesNotHoldUpEarlierDueRetries(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	c := &Impl{topic: "retry", pool: NewWorkerPool("retry", 1, false), pending: newPendingRetries(2)}
+	tracker := NewOffsetTracker("retry")
+	dispatched := make(chan int64, 2)
+	dispatcher := func(_ context.Context, msg kafka.Message, _, _ producer.Producer) error {
+		dispatched <- msg.Offset
+		return nil
+	}
+	later := collectionRequest(validRequest, RetryNotBeforeHeader,
+		time.Now().Add(time.Hour).Format(time.RFC3339Nano))
+	later.Offset = 1
+	earlier := collectionRequest(validRequest, RetryNotBeforeHeader,
+		time.Now().Add(20*time.Millisecond).Format(time.RFC3339Nano))
+	earlier.Offset = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c.schedule(ctx, tracker, later, dispatcher, nil, nil)
+	c.schedule(ctx, tracker, earlier, dispatcher, nil, nil)
+	select {
+	case offset := <-dispatched:
+		assert.Equal(t, earlier.Offset, offset)
+	case <-time.After(time.Second):
+		t.Fatal("the earlier due retry is held up by the later one")
+	}
+
+	cancel()
+	tracker.Wait()
+	assert.Empty(t, dispatched, "the pending retry is redelivered after the shutdown")
+	assert.Empty(t, tracker.Ready(), "the pending retry is not committed")
+}
+
+func TestScheduleBoundsPendingRetries(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	c := &Impl{topic: "retry", pool: NewWorkerPool("retry", 1, false), pending: newPendingRetries(1)}
+	tracker := NewOffsetTracker("retry")
+	dispatcher := func(context.Context, kafka.Message, producer.Producer, producer.Producer) error {
+		return nil
+	}
+	future := time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	first := collectionRequest(validRequest, RetryNotBeforeHeader, future)
+	second := collectionRequest(validRequest, RetryNotBeforeHeader, future)
+	second.Offset = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c.schedule(ctx, tracker, first, dispatcher, nil, nil)
+	scheduled := make(chan struct{})
+	go func() {
+		defer close(scheduled)
+		c.schedule(ctx, tracker, second, dispatcher, nil, nil)
+	}()
+	select {
+	case <-scheduled:
+		t.Fatal("fetching goes on with too many pending retries")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	<-scheduled
+	tracker.Wait()
+	assert.Empty(t, tracker.Ready())
+}
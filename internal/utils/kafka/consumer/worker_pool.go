@@ -0,0 +1,117 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/clients/commonclient/model"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+// WorkerPool bounds the number of collection requests processed at once. A worker is acquired
+// before dispatching a fetched message that is due, so fetching pauses while all of them are busy.
+// When customers are serialized, the requests of a busy customer wait for the running one without
+// holding a worker, and run in fetch order. A pool shared by several consumers bounds and serializes
+// their requests together.
+type WorkerPool struct {
+	topic     string
+	workers   chan struct{}
+	serialize bool
+	mu        sync.Mutex
+	// customers holds the queued jobs of every customer being collected
+	customers map[string][]func()
+}
+
+// NewWorkerPool creates a pool of workers, topic labels its busy workers gauge.
+func NewWorkerPool(topic string, workers int, serialize bool) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &WorkerPool{
+		topic:     topic,
+		workers:   make(chan struct{}, workers),
+		serialize: serialize,
+		customers: make(map[string][]func()),
+	}
+}
+
+// Acquire blocks until a worker is free, or ctx is done.
+func (p *WorkerPool) Acquire(ctx context.Context) error {
+	select {
+	case p.workers <- struct{}{}:
+		p.updateBusy()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees an acquired worker.
+func (p *WorkerPool) Release() {
+	<-p.workers
+	p.updateBusy()
+}
+
+// Go runs job on an acquired worker and releases it once done. With an empty customerID the job
+// is never serialized. A job queued behind a busy customer gives its worker back and takes one
+// again when its turn comes, so the backlog of a customer never holds the workers of the others.
+func (p *WorkerPool) Go(customerID string, job func()) {
+	if !p.serialize || customerID == "" {
+		go func() {
+			defer p.Release()
+			job()
+		}()
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if queue, busy := p.customers[customerID]; busy {
+		p.customers[customerID] = append(queue, job)
+		p.Release()
+		return
+	}
+	p.customers[customerID] = nil
+	go p.runCustomer(customerID, job)
+}
+
+// runCustomer runs job then the jobs queued for the customer meanwhile, each on a worker of its own.
+func (p *WorkerPool) runCustomer(customerID string, job func()) {
+	for job != nil {
+		job()
+		p.Release()
+		p.mu.Lock()
+		if queue := p.customers[customerID]; len(queue) > 0 {
+			job, p.customers[customerID] = queue[0], queue[1:]
+		} else {
+			delete(p.customers, customerID)
+			job = nil
+		}
+		p.mu.Unlock()
+		if job != nil {
+			// the queued jobs are told about the shutdown by their own context
+			p.workers <- struct{}{}
+			p.updateBusy()
+		}
+	}
+}
+
+func (p *WorkerPool) updateBusy() {
+	prometheus.KafkaConsumerBusyWorkers.WithLabelValues(p.topic).Set(float64(len(p.workers)))
+}
+
+// customerOf returns the customer a collection request is made for, or an empty string when the
+// request cannot be decoded. Malformed requests are rejected by the dispatcher.
+func customerOf(msg kafka.Message) string {
+	var input model.KafkaCollectionRequest
+	if err := json.Unmarshal(msg.Value, &input); err != nil {
+		return ""
+	}
+	if input.ApplicationCustomerID != "" {
+		return input.ApplicationCustomerID
+	}
+	return input.PlatformCustomerID
+}
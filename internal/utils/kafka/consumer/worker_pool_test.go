@@ -0,0 +1,115 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package consumer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/handlers/prometheus"
+)
+
+func TestWorkerPoolBlocksWhenBusy(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	pool := NewWorkerPool("collection", 2, false)
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		require.NoError(t, pool.Acquire(context.Background()))
+		pool.Go("", func() { <-release })
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Acquire(ctx), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, pool.Acquire(context.Background()))
+	pool.Release()
+}
+
+func TestWorkerPoolSerializesCustomers(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	pool := NewWorkerPool("collection", 4, true)
+	var (
+		mu      sync.Mutex
+		order   []int
+		running = make(map[string]int)
+		overlap bool
+		wg      sync.WaitGroup
+	)
+	job := func(customerID string, i int) func() {
+		return func() {
+			defer wg.Done()
+			mu.Lock()
+			running[customerID]++
+			overlap = overlap || running[customerID] > 1
+			if customerID == "a" {
+				order = append(order, i)
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			running[customerID]--
+			mu.Unlock()
+		}
+	}
+	for i, customerID := range []string{"a", "a", "b", "a"} {
+		wg.Add(1)
+		require.NoError(t, pool.Acquire(context.Background()))
+		pool.Go(customerID, job(customerID, i))
+	}
+	wg.Wait()
+
+	assert.False(t, overlap, "a customer was collected twice at once")
+	assert.Equal(t, []int{0, 1, 3}, order)
+	assert.Eventually(t, func() bool {
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+		return len(pool.workers) == 0 && len(pool.customers) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestWorkerPoolBacklogDoesNotBlockOtherCustomers(t *testing.T) {
+	prometheus.NewMetricCollectionHandler().SetupMetricCollector()
+	pool := NewWorkerPool("collection", 2, true)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		require.NoError(t, pool.Acquire(context.Background()))
+		pool.Go("a", func() {
+			defer wg.Done()
+			<-release
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, pool.Acquire(ctx), "the queued jobs of a hold no worker")
+	done := make(chan struct{})
+	pool.Go("b", func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("customer b is blocked by the backlog of a")
+	}
+
+	close(release)
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+		return len(pool.workers) == 0 && len(pool.customers) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestCustomerOf(t *testing.T) {
+	assert.Equal(t, "a", customerOf(collectionRequest(validRequest)))
+	assert.Equal(t, "p", customerOf(collectionRequest(`{"platform_customer_id":"p"}`)))
+	assert.Empty(t, customerOf(collectionRequest(`{"platform_customer_id":`)))
+}
@@ -0,0 +1,62 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package producer
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	tildeerrors "github.hpe.com/cloud/tilde-common/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/auth"
+)
+
+// WriterCloser is implemented by the producers and forwarders.
+type WriterCloser interface {
+	CloseWriter() error
+}
+
+// Forwarder writes consumed messages to another topic, such as a dead-letter topic, keeping
+// their key, headers and payload.
+type Forwarder interface {
+	CloseWriter() error
+
+	// Forward writes msg with headers appended to the original ones.
+	Forward(ctx context.Context, msg kafka.Message, headers ...kafka.Header) error
+}
+
+type ForwarderImpl struct {
+	client WriterClient
+	writer *kafka.Writer
+}
+
+// NewForwarder creates a forwarder writing to topic.
+func NewForwarder(security auth.Settings, brokers, topic string) Forwarder {
+	return &ForwarderImpl{
+		client: &WriterClientImpl{},
+		writer: newWriter(security, brokers, topic),
+	}
+}
+
+func (f *ForwarderImpl) CloseWriter() error {
+	logger.Info("Forwarder close")
+	return f.client.Close(f.writer)
+}
+
+func (f *ForwarderImpl) Forward(ctx context.Context, msg kafka.Message, headers ...kafka.Header) error {
+	forwarded := kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: append(append([]kafka.Header(nil), msg.Headers...), headers...),
+	}
+	if err := f.client.WriteMessages(ctx, f.writer, []kafka.Message{forwarded}); err != nil {
+		logger.WithContext(ctx).Error("forwarding message failed ", zap.String("topic", f.writer.Topic),
+			zap.Error(err))
+		return tildeerrors.NewInternalError("Kafka write failed", err)
+	}
+	logger.WithContext(ctx).Info("Message forwarded",
+		zap.String("topic", f.writer.Topic),
+		zap.String("key", string(forwarded.Key)))
+	return nil
+}
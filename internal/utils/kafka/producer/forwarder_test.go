@@ -0,0 +1,58 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package producer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/auth"
+)
+
+type fakeWriterClient struct {
+	written []kafka.Message
+	err     error
+}
+
+func (c *fakeWriterClient) WriteMessages(_ context.Context, _ *kafka.Writer, msgs []kafka.Message) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.written = append(c.written, msgs...)
+	return nil
+}
+
+func (c *fakeWriterClient) Close(*kafka.Writer) error {
+	return nil
+}
+
+func TestForwarderKeepsMessage(t *testing.T) {
+	client := &fakeWriterClient{}
+	forwarder := &ForwarderImpl{client: client, writer: newWriter(auth.Settings{}, "localhost:9092", "dlq")}
+	msg := kafka.Message{
+		Topic:     "collection",
+		Partition: 2,
+		Offset:    7,
+		Key:       []byte("customer"),
+		Value:     []byte(`{"collection_id":`),
+		Headers:   []kafka.Header{{Key: "ce_type", Value: []byte("t")}},
+	}
+	require.NoError(t, forwarder.Forward(context.Background(), msg, kafka.Header{Key: "reason", Value: []byte("r")}))
+
+	require.Len(t, client.written, 1)
+	forwarded := client.written[0]
+	assert.Empty(t, forwarded.Topic, "the writer topic is used")
+	assert.Equal(t, msg.Key, forwarded.Key)
+	assert.Equal(t, msg.Value, forwarded.Value)
+	assert.Equal(t, []kafka.Header{{Key: "ce_type", Value: []byte("t")}, {Key: "reason", Value: []byte("r")}},
+		forwarded.Headers)
+	assert.Len(t, msg.Headers, 1, "the original message is left untouched")
+
+	client.err = errors.New("broker down")
+	assert.Error(t, forwarder.Forward(context.Background(), msg))
+}
@@ -4,11 +4,11 @@ package producer
 
 import (
 	"context"
-	"crypto/tls"
 	"strings"
 	"time"
 
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/contextutilities"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/kafka/auth"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/logging"
 	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/tracer"
 
@@ -52,23 +52,24 @@ func (p *Impl) CloseWriter() error {
 	return p.client.Close(p.writer)
 }
 
-func NewProducer(sslMode bool, brokers, topic string, uuidGen uuidgenerator.Generator) Producer {
-	writer := &kafka.Writer{
-		Topic:        topic,
-		Addr:         kafka.TCP(strings.Split(brokers, ",")[0]),
-		RequiredAcks: kafka.RequireAll,
-	}
-	// This will be false during development
-	if sslMode {
-		writer.Transport = &kafka.Transport{TLS: &tls.Config{MinVersion: tls.VersionTLS12}}
-	}
+func NewProducer(security auth.Settings, brokers, topic string, uuidGen uuidgenerator.Generator) Producer {
 	return &Impl{
 		client:  &WriterClientImpl{},
-		writer:  writer,
+		writer:  newWriter(security, brokers, topic),
 		uuidGen: uuidGen,
 	}
 }
 
+func newWriter(security auth.Settings, brokers, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Topic:        topic,
+		Addr:         kafka.TCP(strings.Split(brokers, ",")[0]),
+		RequiredAcks: kafka.RequireAll,
+		// This will be plaintext during development
+		Transport: security.Transport(),
+	}
+}
+
 func (p *Impl) PublishMessage(
 	ctx context.Context,
 	collectionID, customerID, eventType, eventSource string, eventTime time.Time,
@@ -0,0 +1,125 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var (
+	errInvalidKey        = errors.New("secrets key must be a hex encoded 16, 24 or 32 bytes AES key")
+	errInvalidCiphertext = errors.New("secrets file is too short")
+
+	json = jsoniter.ConfigCompatibleWithStandardLibrary
+)
+
+// EncryptedFileProvider reads the secrets from a local file holding a JSON object of secrets by
+// name, encrypted with AES-GCM and base64 encoded, see EncryptSecrets. It is meant for local
+// runs where no secret store is available. The file is decrypted again once it changes.
+type EncryptedFileProvider struct {
+	path    string
+	aead    cipher.AEAD
+	mu      sync.Mutex
+	secrets map[string]string
+	modTime time.Time
+}
+
+// NewEncryptedFileProvider returns a provider decrypting path with the hex encoded AES key.
+func NewEncryptedFileProvider(path, hexKey string) (*EncryptedFileProvider, error) {
+	aead, err := newAEAD(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedFileProvider{path: path, aead: aead}, nil
+}
+
+func newAEAD(hexKey string) (cipher.AEAD, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, errInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errInvalidKey
+	}
+	return cipher.NewGCM(block)
+}
+
+func (p *EncryptedFileProvider) Get(_ context.Context, name string) (string, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.secrets == nil || !p.modTime.Equal(info.ModTime()) {
+		content, err := os.ReadFile(p.path)
+		if err != nil {
+			return "", err
+		}
+		secrets, err := decryptSecrets(p.aead, content)
+		if err != nil {
+			return "", err
+		}
+		p.secrets, p.modTime = secrets, info.ModTime()
+	}
+	value, found := p.secrets[name]
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// EncryptSecrets returns the content of an encrypted secrets file holding secrets.
+func EncryptSecrets(hexKey string, secrets map[string]string) ([]byte, error) {
+	aead, err := newAEAD(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(encoded, sealed)
+	return encoded, nil
+}
+
+func decryptSecrets(aead cipher.AEAD, content []byte) (map[string]string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errInvalidCiphertext
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, err
+	}
+	secrets := make(map[string]string)
+	if err = json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
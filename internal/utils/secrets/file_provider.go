@@ -0,0 +1,60 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cachedSecret struct {
+	value   string
+	modTime time.Time
+}
+
+// FileProvider reads the secrets from the files of a directory, one file per secret as mounted
+// from a Kubernetes secret. A file is read again once its modification time changes, so
+// rotated secrets are picked up when the kubelet swaps the mounted files.
+type FileProvider struct {
+	dir   string
+	mu    sync.Mutex
+	cache map[string]cachedSecret
+}
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir, cache: make(map[string]cachedSecret)}
+}
+
+func (p *FileProvider) Get(_ context.Context, name string) (string, error) {
+	if filepath.Base(name) != name {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	path := filepath.Join(p.dir, name)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cached, found := p.cache[name]; found && cached.modTime.Equal(info.ModTime()) {
+		return cached.value, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(content))
+	p.cache[name] = cachedSecret{value: value, modTime: info.ModTime()}
+	return value, nil
+}
@@ -0,0 +1,111 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/configs"
+	"github.hpe.com/nimble-dcs/panorama-common-hauler/internal/utils/logging"
+)
+
+// Secret names. The environment provider looks them up upper cased, e.g. AWS_ACCESS_KEY_ID.
+const (
+	AWSAccessKeyID     = "aws_access_key_id"
+	AWSSecretAccessKey = "aws_secret_access_key"
+	ClientID           = "client_id"
+	ClientSecret       = "client_secret"
+	KafkaUsername      = "kafka_username"
+	KafkaPassword      = "kafka_password"
+)
+
+// Provider kinds, see configs.GetSecretsProvider
+const (
+	EnvKind           = "env"
+	FileKind          = "file"
+	EncryptedFileKind = "encrypted-file"
+)
+
+var (
+	ErrSecretNotFound  = errors.New("secret not found")
+	errUnknownProvider = errors.New("unknown secrets provider")
+
+	logger = logging.GetLogger()
+
+	defaultProvider   SecretsProvider
+	defaultProviderMu sync.Mutex
+)
+
+// SecretsProvider returns the secrets of the hauler by name. Implementations pick up rotated
+// secrets without a restart.
+type SecretsProvider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// FromConfig builds the provider selected by configuration.
+func FromConfig() (SecretsProvider, error) {
+	switch kind := configs.GetSecretsProvider(); kind {
+	case EnvKind:
+		return EnvProvider{}, nil
+	case FileKind:
+		return NewFileProvider(configs.GetSecretsDir()), nil
+	case EncryptedFileKind:
+		return NewEncryptedFileProvider(configs.GetSecretsFile(), configs.GetSecretsKey())
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownProvider, kind)
+	}
+}
+
+// Default returns the process wide provider, built from configuration on first use. It returns
+// the error of an invalid configuration, checked at startup so the hauler never runs without
+// its credentials.
+func Default() (SecretsProvider, error) {
+	defaultProviderMu.Lock()
+	defer defaultProviderMu.Unlock()
+	if defaultProvider == nil {
+		provider, err := FromConfig()
+		if err != nil {
+			return nil, err
+		}
+		defaultProvider = provider
+	}
+	return defaultProvider, nil
+}
+
+// SetDefault replaces the process wide provider.
+func SetDefault(provider SecretsProvider) {
+	defaultProviderMu.Lock()
+	defer defaultProviderMu.Unlock()
+	defaultProvider = provider
+}
+
+// GetOrEmpty returns the named secret of the default provider, or an empty string when it
+// is not set or cannot be read.
+func GetOrEmpty(ctx context.Context, name string) string {
+	provider, err := Default()
+	if err != nil {
+		logger.WithContext(ctx).Errorf("Secrets provider creation failed : %v", err)
+		return ""
+	}
+	value, err := provider.Get(ctx, name)
+	if err != nil && !errors.Is(err, ErrSecretNotFound) {
+		logger.WithContext(ctx).Errorf("Reading secret %s failed : %v", name, err)
+	}
+	return value
+}
+
+// EnvProvider reads the secrets from the environment variables.
+type EnvProvider struct{}
+
+func (EnvProvider) Get(_ context.Context, name string) (string, error) {
+	value, found := os.LookupEnv(strings.ToUpper(name))
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
@@ -0,0 +1,92 @@
+// (C) Copyright 2023 Hewlett Packard Enterprise Development LP
+
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("KAFKA_PASSWORD", "password")
+	value, err := EnvProvider{}.Get(context.Background(), KafkaPassword)
+	assert.NoError(t, err)
+	assert.Equal(t, "password", value)
+
+	_, err = EnvProvider{}.Get(context.Background(), "not_set_anywhere")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestFileProviderReloads(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ClientSecret)
+	assert.NoError(t, os.WriteFile(path, []byte("secret1\n"), 0o600))
+	provider := NewFileProvider(dir)
+	ctx := context.Background()
+
+	value, err := provider.Get(ctx, ClientSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret1", value)
+
+	assert.NoError(t, os.WriteFile(path, []byte("secret2"), 0o600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	value, err = provider.Get(ctx, ClientSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret2", value)
+
+	_, err = provider.Get(ctx, ClientID)
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	_, err = provider.Get(ctx, "../"+ClientSecret)
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestEncryptedFileProvider(t *testing.T) {
+	content, err := EncryptSecrets(testKey, map[string]string{AWSAccessKeyID: "key", AWSSecretAccessKey: "secret"})
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	assert.NoError(t, os.WriteFile(path, content, 0o600))
+
+	provider, err := NewEncryptedFileProvider(path, testKey)
+	assert.NoError(t, err)
+	value, err := provider.Get(context.Background(), AWSSecretAccessKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", value)
+	_, err = provider.Get(context.Background(), ClientID)
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	otherKey := "ff" + testKey[2:]
+	provider, err = NewEncryptedFileProvider(path, otherKey)
+	assert.NoError(t, err)
+	_, err = provider.Get(context.Background(), AWSSecretAccessKey)
+	assert.Error(t, err)
+
+	_, err = NewEncryptedFileProvider(path, "not-hex")
+	assert.ErrorIs(t, err, errInvalidKey)
+}
+
+func TestDefaultProvider(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ClientID), []byte("id"), 0o600))
+	SetDefault(NewFileProvider(dir))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	assert.Equal(t, "id", GetOrEmpty(context.Background(), ClientID))
+	assert.Equal(t, "", GetOrEmpty(context.Background(), ClientSecret))
+}
+
+func TestDefaultProviderInvalidConfig(t *testing.T) {
+	SetDefault(nil)
+	t.Setenv("SECRETS_PROVIDER", "vault")
+	t.Cleanup(func() { SetDefault(nil) })
+
+	_, err := Default()
+	assert.ErrorIs(t, err, errUnknownProvider)
+	assert.Equal(t, "", GetOrEmpty(context.Background(), ClientID), "no fallback to the environment")
+}